@@ -0,0 +1,343 @@
+// Package analyze runs classic statistical tests of
+// randomness over sequences of decimal digits. The digits are
+// streamed, so only the counters of the tests are held in
+// memory.
+package analyze
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/targodan/piio"
+)
+
+// pokerHandSize is the amount of digits per hand of the
+// poker test.
+const pokerHandSize = 5
+
+// pokerProbabilities are the probabilities of a hand of
+// five random digits containing two or fewer, three, four
+// and five distinct digits. Hands of one and two distinct
+// digits are combined, as one alone is too rare.
+var pokerProbabilities = [...]float64{0.0136, 0.18, 0.504, 0.3024}
+
+// maxGap is the length of the longest gap counted on its
+// own by the gap test. Longer gaps are counted together.
+const maxGap = 30
+
+// ChiSquareResult is the result of a chi-square test.
+type ChiSquareResult struct {
+	// Counts are the observed counts of the categories.
+	Counts           []int64 `json:"counts"`
+	ChiSquare        float64 `json:"chiSquare"`
+	DegreesOfFreedom int     `json:"degreesOfFreedom"`
+	PValue           float64 `json:"pValue"`
+}
+
+// SerialResult is the result of the serial test of
+// consecutive pairs of digits.
+type SerialResult struct {
+	// Transitions counts how often digit j follows digit i
+	// at index [i][j].
+	Transitions [10][10]int64 `json:"transitions"`
+	// Statistic is Good's serial statistic, which is
+	// chi-square distributed.
+	Statistic        float64 `json:"statistic"`
+	DegreesOfFreedom int     `json:"degreesOfFreedom"`
+	PValue           float64 `json:"pValue"`
+}
+
+// GapResult is the result of the gap test of a digit, i.e.
+// of the amounts of other digits between two occurrences.
+type GapResult struct {
+	Digit int `json:"digit"`
+	ChiSquareResult
+}
+
+// RunsResult is the result of the runs test of the digits
+// below and above the median, i.e. 0 to 4 and 5 to 9.
+type RunsResult struct {
+	Runs     int64   `json:"runs"`
+	Expected float64 `json:"expected"`
+	Z        float64 `json:"z"`
+	PValue   float64 `json:"pValue"`
+}
+
+// LongestRunResult describes the longest run of a repeated
+// digit, like the six nines of the Feynman point.
+type LongestRunResult struct {
+	Digit      int   `json:"digit"`
+	Length     int64 `json:"length"`
+	FirstIndex int64 `json:"firstIndex"`
+	// PValue is the probability of a run at least as long
+	// within the same amount of random digits.
+	PValue float64 `json:"pValue"`
+}
+
+// Report holds the results of all tests.
+type Report struct {
+	Range      piio.Range       `json:"range"`
+	Frequency  ChiSquareResult  `json:"frequency"`
+	Serial     SerialResult     `json:"serial"`
+	Poker      ChiSquareResult  `json:"poker"`
+	Gap        []GapResult      `json:"gap"`
+	Runs       RunsResult       `json:"runs"`
+	LongestRun LongestRunResult `json:"longestRun"`
+}
+
+// Analyzer runs the tests over the ASCII digits written to
+// it. Like with a CompressWriter, decimal points and
+// whitespace are skipped.
+type Analyzer struct {
+	firstIndex int64
+	n          int64
+
+	counts      [10]int64
+	transitions [10][10]int64
+	prev        int
+
+	hand       [pokerHandSize]byte
+	handSize   int
+	pokerHands [len(pokerProbabilities)]int64
+
+	lastSeen [10]int64
+	gaps     [10][maxGap + 1]int64
+
+	low, high int64
+	runs      int64
+	prevHigh  bool
+
+	run        int64
+	longest    int64
+	longestAt  int64
+	longestDig int
+}
+
+// NewAnalyzer creates a new Analyzer for the digits starting
+// at the given index.
+func NewAnalyzer(firstIndex int64) *Analyzer {
+	a := &Analyzer{
+		firstIndex: firstIndex,
+		prev:       -1,
+	}
+	for d := range a.lastSeen {
+		a.lastSeen[d] = -1
+	}
+	return a
+}
+
+// Write adds the digits to the tests.
+func (a *Analyzer) Write(p []byte) (int, error) {
+	for i, c := range p {
+		if c < '0' || c > '9' {
+			if c == '.' || c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+				continue
+			}
+			return i, &piio.InvalidArgumentError{Reason: fmt.Sprintf("unexpected character %q", c)}
+		}
+		a.add(int(c - '0'))
+	}
+	return len(p), nil
+}
+
+func (a *Analyzer) add(d int) {
+	a.counts[d]++
+
+	if a.prev >= 0 {
+		a.transitions[a.prev][d]++
+	}
+
+	a.hand[a.handSize] = byte(d)
+	a.handSize++
+	if a.handSize == pokerHandSize {
+		a.pokerHands[distinct(a.hand[:])-2]++
+		a.handSize = 0
+	}
+
+	if last := a.lastSeen[d]; last >= 0 {
+		gap := a.n - last - 1
+		if gap > maxGap {
+			gap = maxGap
+		}
+		a.gaps[d][gap]++
+	}
+	a.lastSeen[d] = a.n
+
+	high := d >= 5
+	if high {
+		a.high++
+	} else {
+		a.low++
+	}
+	if a.n == 0 || high != a.prevHigh {
+		a.runs++
+	}
+	a.prevHigh = high
+
+	if d == a.prev {
+		a.run++
+	} else {
+		a.run = 1
+	}
+	if a.run > a.longest {
+		a.longest = a.run
+		a.longestAt = a.firstIndex + a.n - a.run + 1
+		a.longestDig = d
+	}
+
+	a.prev = d
+	a.n++
+}
+
+// distinct returns the amount of distinct digits of the
+// hand, counting a single one as two.
+func distinct(hand []byte) int {
+	var seen [10]bool
+	n := 0
+	for _, d := range hand {
+		if !seen[d] {
+			seen[d] = true
+			n++
+		}
+	}
+	if n < 2 {
+		return 2
+	}
+	return n
+}
+
+// Digits returns the amount of digits analyzed.
+func (a *Analyzer) Digits() int64 {
+	return a.n
+}
+
+// Report returns the results of the tests over all digits
+// written so far.
+func (a *Analyzer) Report() *Report {
+	r := &Report{
+		Range: piio.Range{FirstIndex: a.firstIndex, Size: a.n},
+	}
+
+	uniform := make([]float64, 10)
+	for d := range uniform {
+		uniform[d] = 0.1
+	}
+	r.Frequency = chiSquareTest(a.counts[:], uniform)
+	r.Serial = a.serial()
+	r.Poker = chiSquareTest(a.pokerHands[:], pokerProbabilities[:])
+
+	geometric := make([]float64, maxGap+1)
+	for g := 0; g < maxGap; g++ {
+		geometric[g] = 0.1 * math.Pow(0.9, float64(g))
+	}
+	geometric[maxGap] = math.Pow(0.9, maxGap)
+	for d := range a.gaps {
+		r.Gap = append(r.Gap, GapResult{
+			Digit:           d,
+			ChiSquareResult: chiSquareTest(a.gaps[d][:], geometric),
+		})
+	}
+
+	r.Runs = a.runsTest()
+	r.LongestRun = LongestRunResult{
+		Digit:      a.longestDig,
+		Length:     a.longest,
+		FirstIndex: a.longestAt,
+		PValue:     1,
+	}
+	if a.longest > 0 {
+		// The expected amount of runs of at least this length
+		// is small, so their amount is roughly Poisson
+		// distributed.
+		expected := float64(a.n) * 0.9 * math.Pow(0.1, float64(a.longest-1))
+		r.LongestRun.PValue = -math.Expm1(-expected)
+	}
+	return r
+}
+
+// chiSquareTest compares the counts with the expected
+// probabilities of the categories.
+func chiSquareTest(counts []int64, probabilities []float64) ChiSquareResult {
+	result := ChiSquareResult{
+		Counts:           append([]int64{}, counts...),
+		DegreesOfFreedom: len(counts) - 1,
+		PValue:           1,
+	}
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	if total == 0 {
+		return result
+	}
+	for i, n := range counts {
+		expected := float64(total) * probabilities[i]
+		diff := float64(n) - expected
+		result.ChiSquare += diff * diff / expected
+	}
+	result.PValue = chiSquarePValue(result.ChiSquare, result.DegreesOfFreedom)
+	return result
+}
+
+// serial computes Good's serial statistic, the difference of
+// the statistics of the pairs and the single digits.
+func (a *Analyzer) serial() SerialResult {
+	result := SerialResult{
+		Transitions:      a.transitions,
+		DegreesOfFreedom: 90,
+		PValue:           1,
+	}
+	if a.n < 2 {
+		return result
+	}
+	n := float64(a.n)
+	var singles, pairs float64
+	for i := range a.counts {
+		singles += float64(a.counts[i]) * float64(a.counts[i])
+		for j := range a.transitions[i] {
+			pairs += float64(a.transitions[i][j]) * float64(a.transitions[i][j])
+		}
+	}
+	psi1 := 10/n*singles - n
+	psi2 := 100/(n-1)*pairs - (n - 1)
+	result.Statistic = psi2 - psi1
+	result.PValue = chiSquarePValue(result.Statistic, result.DegreesOfFreedom)
+	return result
+}
+
+// runsTest compares the amount of runs of digits below and
+// above the median with the expected one.
+func (a *Analyzer) runsTest() RunsResult {
+	result := RunsResult{
+		Runs:   a.runs,
+		PValue: 1,
+	}
+	if a.low == 0 || a.high == 0 {
+		return result
+	}
+	n1, n2 := float64(a.low), float64(a.high)
+	n := n1 + n2
+	result.Expected = 2*n1*n2/n + 1
+	variance := 2 * n1 * n2 * (2*n1*n2 - n) / (n * n * (n - 1))
+	if variance <= 0 {
+		return result
+	}
+	result.Z = (float64(a.runs) - result.Expected) / math.Sqrt(variance)
+	result.PValue = normalPValue(result.Z)
+	return result
+}
+
+// Analyze runs the tests over the given range of digits of
+// the source.
+func Analyze(cs piio.ChunkSource, firstIndex, size int64) (*Report, error) {
+	if size <= 0 {
+		return nil, &piio.InvalidArgumentError{Reason: "the size must be positive"}
+	}
+	a := NewAnalyzer(firstIndex)
+	_, err := io.Copy(a, piio.NewChunkSourceReader(cs, firstIndex, size))
+	if err != nil {
+		return nil, err
+	}
+	return a.Report(), nil
+}
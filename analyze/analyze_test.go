@@ -0,0 +1,90 @@
+package analyze
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/targodan/piio"
+	"github.com/targodan/piio/chudnovsky"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPValues(t *testing.T) {
+	Convey("The chi-square p-values should match the closed forms.", t, func() {
+		for _, x := range []float64{0.1, 1, 2.5, 10, 40} {
+			So(chiSquarePValue(x, 2), ShouldAlmostEqual, math.Exp(-x/2), 1e-12)
+			So(chiSquarePValue(x, 1), ShouldAlmostEqual, math.Erfc(math.Sqrt(x/2)), 1e-12)
+		}
+		So(chiSquarePValue(0, 9), ShouldEqual, 1)
+		So(chiSquarePValue(16.919, 9), ShouldAlmostEqual, 0.05, 1e-4)
+	})
+	Convey("The normal p-values should be two-sided.", t, func() {
+		So(normalPValue(1.959964), ShouldAlmostEqual, 0.05, 1e-6)
+		So(normalPValue(-1.959964), ShouldAlmostEqual, 0.05, 1e-6)
+	})
+}
+
+func TestAnalyzer(t *testing.T) {
+	Convey("Given the digits of pi", t, func() {
+		digits, err := chudnovsky.Pi(context.Background(), 100000, chudnovsky.Config{})
+		So(err, ShouldBeNil)
+		a := NewAnalyzer(0)
+		_, err = a.Write(digits)
+		So(err, ShouldBeNil)
+		r := a.Report()
+
+		Convey("no test should reject them.", func() {
+			So(a.Digits(), ShouldEqual, 100000)
+			So(r.Range, ShouldResemble, piio.Range{FirstIndex: 0, Size: 100000})
+			So(r.Frequency.PValue, ShouldBeGreaterThan, 0.001)
+			So(r.Serial.PValue, ShouldBeGreaterThan, 0.001)
+			So(r.Poker.PValue, ShouldBeGreaterThan, 0.001)
+			So(r.Runs.PValue, ShouldBeGreaterThan, 0.001)
+			So(r.Gap, ShouldHaveLength, 10)
+			for _, g := range r.Gap {
+				So(g.PValue, ShouldBeGreaterThan, 0.001)
+			}
+		})
+		Convey("the Feynman point should be the longest run.", func() {
+			So(r.LongestRun.Digit, ShouldEqual, 9)
+			So(r.LongestRun.Length, ShouldEqual, 6)
+			So(r.LongestRun.FirstIndex, ShouldEqual, 762)
+		})
+		Convey("the counts should add up.", func() {
+			var total int64
+			for _, n := range r.Frequency.Counts {
+				total += n
+			}
+			So(total, ShouldEqual, 100000)
+			So(r.Poker.Counts[0]+r.Poker.Counts[1]+r.Poker.Counts[2]+r.Poker.Counts[3], ShouldEqual, 100000/pokerHandSize)
+		})
+	})
+	Convey("Given a regular sequence", t, func() {
+		a := NewAnalyzer(10)
+		_, err := a.Write([]byte(strings.Repeat("0123456789", 1000)))
+		So(err, ShouldBeNil)
+		r := a.Report()
+
+		Convey("the uniform frequencies should pass.", func() {
+			So(r.Frequency.ChiSquare, ShouldEqual, 0)
+			So(r.Frequency.PValue, ShouldEqual, 1)
+		})
+		Convey("the serial and runs tests should reject it.", func() {
+			So(r.Serial.Transitions[3][4], ShouldEqual, 1000)
+			So(r.Serial.PValue, ShouldBeLessThan, 1e-6)
+			So(r.Runs.PValue, ShouldBeLessThan, 1e-6)
+		})
+		Convey("the index of the longest run should be offset.", func() {
+			So(r.LongestRun.Length, ShouldEqual, 1)
+			So(r.LongestRun.FirstIndex, ShouldEqual, 10)
+		})
+	})
+	Convey("Writing unexpected characters should fail.", t, func() {
+		_, err := NewAnalyzer(0).Write([]byte("3.14x"))
+		So(errors.Is(err, piio.ErrInvalidArgument), ShouldBeTrue)
+	})
+}
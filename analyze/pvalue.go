@@ -0,0 +1,78 @@
+package analyze
+
+import "math"
+
+const (
+	gammaIterations = 1000
+	gammaEpsilon    = 1e-15
+)
+
+// chiSquarePValue returns the probability of a chi-square
+// statistic of at least x with the given degrees of freedom.
+func chiSquarePValue(x float64, df int) float64 {
+	if df <= 0 || x <= 0 {
+		return 1
+	}
+	return upperGamma(float64(df)/2, x/2)
+}
+
+// normalPValue returns the two-sided probability of a
+// standard normal value of at least the magnitude of z.
+func normalPValue(z float64) float64 {
+	return math.Erfc(math.Abs(z) / math.Sqrt2)
+}
+
+// upperGamma returns the regularized upper incomplete gamma
+// function Q(a, x).
+func upperGamma(a, x float64) float64 {
+	if x < a+1 {
+		return 1 - lowerGammaSeries(a, x)
+	}
+	return upperGammaFraction(a, x)
+}
+
+// lowerGammaSeries evaluates the series of the regularized
+// lower incomplete gamma function P(a, x).
+func lowerGammaSeries(a, x float64) float64 {
+	lg, _ := math.Lgamma(a)
+	term := 1 / a
+	sum := term
+	for n := 1; n < gammaIterations; n++ {
+		term *= x / (a + float64(n))
+		sum += term
+		if math.Abs(term) < math.Abs(sum)*gammaEpsilon {
+			break
+		}
+	}
+	return sum * math.Exp(-x+a*math.Log(x)-lg)
+}
+
+// upperGammaFraction evaluates the continued fraction of
+// Q(a, x) using the modified Lentz method.
+func upperGammaFraction(a, x float64) float64 {
+	const tiny = 1e-300
+	lg, _ := math.Lgamma(a)
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i < gammaIterations; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < gammaEpsilon {
+			break
+		}
+	}
+	return math.Exp(-x+a*math.Log(x)-lg) * h
+}
@@ -0,0 +1,129 @@
+package piio
+
+import (
+	"context"
+	"math/bits"
+)
+
+// bbpCheckInterval is the amount of terms summed up between
+// checks of the context.
+const bbpCheckInterval = 1 << 16
+
+// HexDigitsAt computes count hexadecimal digits of pi
+// starting at the given position using the Bailey-Borwein-
+// Plouffe formula. Like the decimal digits, position 0 refers
+// to the 3 in front of the point, so position 1 is the first
+// hexadecimal digit after it. The digits are returned as
+// values between 0 and 15.
+//
+// None of the digits in front of the position are computed,
+// but the time needed still grows slightly faster than
+// linearly with the position.
+func HexDigitsAt(position int64, count int) ([]byte, error) {
+	return HexDigitsAtContext(context.Background(), position, count)
+}
+
+// HexDigitsAtContext does the same as HexDigitsAt, but
+// stops as soon as the context is done.
+func HexDigitsAtContext(ctx context.Context, position int64, count int) ([]byte, error) {
+	err := checkChunkArguments(position, count)
+	if err != nil {
+		return nil, err
+	}
+
+	digits := make([]byte, 0, count)
+	if position == 0 {
+		digits = append(digits, 3)
+		position++
+	}
+	for len(digits) < count {
+		// Position 1 is the first digit after the point.
+		d := position - 1
+		frac, err := bbpFraction(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < bbpReliableDigits(d) && len(digits) < count; i++ {
+			digits = append(digits, byte(frac>>60))
+			frac <<= 4
+			position++
+		}
+	}
+	return digits, nil
+}
+
+// bbpReliableDigits returns the amount of hexadecimal digits
+// of a fraction computed by bbpFraction that are unaffected
+// by the accumulated rounding errors.
+func bbpReliableDigits(d int64) int {
+	// Every term is off by less than one, there are about
+	// 8*d terms in total after weighting. Another digit is
+	// kept as margin for carries.
+	errorBits := bits.Len64(uint64(8 * (d + 16)))
+	return (64-errorBits)/4 - 1
+}
+
+// bbpFraction returns the fractional part of 16^d * pi as a
+// 64 bit fixed point number.
+func bbpFraction(ctx context.Context, d int64) (uint64, error) {
+	var sums [4]uint64
+	for i, j := range [4]uint64{1, 4, 5, 6} {
+		s, err := bbpSeries(ctx, d, j)
+		if err != nil {
+			return 0, err
+		}
+		sums[i] = s
+	}
+	// Overflows are intended, only the fraction is kept.
+	return 4*sums[0] - 2*sums[1] - sums[2] - sums[3], nil
+}
+
+// bbpSeries returns the fractional part of
+// sum_k 16^(d-k) / (8k+j) as a 64 bit fixed point number.
+func bbpSeries(ctx context.Context, d int64, j uint64) (uint64, error) {
+	var sum uint64
+	for k := int64(0); k <= d; k++ {
+		if k%bbpCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+		}
+		m := 8*uint64(k) + j
+		r := powMod(16, uint64(d-k), m)
+		// r < m, so the quotient is floor(r/m * 2^64).
+		q, _ := bits.Div64(r, 0, m)
+		sum += q
+	}
+	for k := d + 1; ; k++ {
+		shift := 4 * (k - d)
+		if shift >= 64 {
+			break
+		}
+		sum += (uint64(1) << uint(64-shift)) / (8*uint64(k) + j)
+	}
+	return sum, nil
+}
+
+// powMod returns b^e mod m.
+func powMod(b, e, m uint64) uint64 {
+	if m == 1 {
+		return 0
+	}
+	result := uint64(1)
+	b %= m
+	for e > 0 {
+		if e&1 == 1 {
+			result = mulMod(result, b, m)
+		}
+		b = mulMod(b, b, m)
+		e >>= 1
+	}
+	return result
+}
+
+// mulMod returns a*b mod m for a, b < m.
+func mulMod(a, b, m uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	_, rem := bits.Div64(hi, lo, m)
+	return rem
+}
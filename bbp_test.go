@@ -0,0 +1,56 @@
+package piio
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const piHex = "3243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89452821E638D01377BE5466CF34E90C6CC0AC29B7C97C50DD3F84D5B5B5470917"
+
+// digitString formats digits of any base up to 16.
+func digitString(digits []byte) string {
+	s := make([]byte, len(digits))
+	for i, d := range digits {
+		s[i] = "0123456789ABCDEF"[d]
+	}
+	return string(s)
+}
+
+func TestHexDigitsAt(t *testing.T) {
+	Convey("Computing hexadecimal digits", t, func() {
+		Convey("should match the known digits.", func() {
+			digits, err := HexDigitsAt(0, len(piHex))
+			So(err, ShouldBeNil)
+			So(digitString(digits), ShouldEqual, piHex)
+		})
+		Convey("should work at any position.", func() {
+			for _, pos := range []int64{1, 7, 33, 100} {
+				digits, err := HexDigitsAt(pos, 20)
+				So(err, ShouldBeNil)
+				So(digitString(digits), ShouldEqual, piHex[pos:pos+20])
+			}
+		})
+		Convey("should work far behind the point.", func() {
+			// Published by Bailey, Borwein and Plouffe.
+			digits, err := HexDigitsAt(1000000, 14)
+			So(err, ShouldBeNil)
+			So(digitString(digits), ShouldEqual, strings.ToUpper("26c65e52cb4593"))
+		})
+		Convey("should reject invalid arguments.", func() {
+			_, err := HexDigitsAt(-1, 2)
+			So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+			_, err = HexDigitsAt(0, 0)
+			So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+		})
+		Convey("should stop once the context is done.", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := HexDigitsAtContext(ctx, 1000, 2)
+			So(err, ShouldEqual, context.Canceled)
+		})
+	})
+}
@@ -0,0 +1,182 @@
+package piio
+
+import (
+	"context"
+	"math"
+	"math/bits"
+	"runtime"
+	"sync"
+)
+
+// bellardDigits is the amount of decimal digits computed
+// per evaluation of the series.
+const bellardDigits = 9
+
+// DecimalDigitsAt computes count decimal digits of pi
+// starting at the given position, using Bellard's variant of
+// Plouffe's algorithm. Position 0 refers to the 3 in front of
+// the decimal point. The digits are returned as values
+// between 0 and 9.
+//
+// Only little memory is needed, but the time grows
+// quadratically with the position, so this is only feasible
+// for positions up to a few ten thousand.
+func DecimalDigitsAt(position int64, count int) ([]byte, error) {
+	return DecimalDigitsAtContext(context.Background(), position, count)
+}
+
+// DecimalDigitsAtContext does the same as DecimalDigitsAt,
+// but stops as soon as the context is done.
+func DecimalDigitsAtContext(ctx context.Context, position int64, count int) ([]byte, error) {
+	err := checkChunkArguments(position, count)
+	if err != nil {
+		return nil, err
+	}
+
+	digits := make([]byte, count)
+	offset := 0
+	if position == 0 {
+		digits[0] = 3
+		offset = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The blocks of digits are independent, so they are
+	// computed in parallel.
+	var once sync.Once
+	var firstErr error
+	wg := &sync.WaitGroup{}
+	workers := make(chan struct{}, runtime.NumCPU())
+	for i := offset; i < count; i += bellardDigits {
+		workers <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-workers }()
+
+			frac, err := bellardFraction(ctx, position+int64(i))
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			for j := i; j < i+bellardDigits && j < count; j++ {
+				var d uint64
+				d, frac = bits.Mul64(frac, 10)
+				digits[j] = byte(d)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return digits, nil
+}
+
+// bellardFraction returns the fractional part of
+// 10^(n-1) * pi as a 64 bit fixed point number, so its
+// leading digits are the digits starting at position n.
+func bellardFraction(ctx context.Context, n int64) (uint64, error) {
+	// Enough terms of the series to make the error of the
+	// omitted ones negligible.
+	N := int64(float64(n+20) * math.Log(10) / math.Log(2))
+
+	var sum uint64
+	for a := int64(3); a <= 2*N; a = nextPrime(a) {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		vmax := int(math.Log(float64(2*N)) / math.Log(float64(a)))
+		av := uint64(1)
+		for i := 0; i < vmax; i++ {
+			av *= uint64(a)
+		}
+
+		var s uint64
+		num := uint64(1)
+		den := uint64(1)
+		v := 0
+		for k := int64(1); k <= N; k++ {
+			t := k
+			for t%a == 0 {
+				t /= a
+				v--
+			}
+			num = mulMod(num, uint64(t), av)
+
+			t = 2*k - 1
+			for t%a == 0 {
+				t /= a
+				v++
+			}
+			den = mulMod(den, uint64(t), av)
+
+			if v > 0 {
+				t := mulMod(invMod(den, av), num, av)
+				t = mulMod(t, uint64(k)%av, av)
+				for i := v; i < vmax; i++ {
+					t = mulMod(t, uint64(a), av)
+				}
+				s += t
+				if s >= av {
+					s -= av
+				}
+			}
+		}
+
+		s = mulMod(s, powMod(10, uint64(n-1), av), av)
+		// s < av, so the quotient is floor(s/av * 2^64).
+		q, _ := bits.Div64(s, 0, av)
+		// Overflows are intended, only the fraction is kept.
+		sum += q
+	}
+	return sum, nil
+}
+
+// invMod returns the inverse of x modulo m. x and m have to
+// be coprime.
+func invMod(x, m uint64) uint64 {
+	var u, v int64 = 0, 1
+	c, d := int64(m), int64(x%m)
+	for d != 0 {
+		q := c / d
+		c, d = d, c-q*d
+		u, v = v, u-q*v
+	}
+	if u < 0 {
+		u += int64(m)
+	}
+	return uint64(u)
+}
+
+// nextPrime returns the smallest prime larger than n.
+func nextPrime(n int64) int64 {
+	for {
+		n++
+		if isPrime(n) {
+			return n
+		}
+	}
+}
+
+func isPrime(n int64) bool {
+	if n < 2 {
+		return false
+	}
+	if n%2 == 0 {
+		return n == 2
+	}
+	for d := int64(3); d*d <= n; d += 2 {
+		if n%d == 0 {
+			return false
+		}
+	}
+	return true
+}
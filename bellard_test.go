@@ -0,0 +1,43 @@
+package piio
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/targodan/piio/chudnovsky"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDecimalDigitsAt(t *testing.T) {
+	Convey("Computing decimal digits", t, func() {
+		Convey("should match the known digits.", func() {
+			digits, err := DecimalDigitsAt(0, 1+len(piDecimals))
+			So(err, ShouldBeNil)
+			So(digitString(digits), ShouldEqual, "3"+piDecimals)
+		})
+		Convey("should work at any position.", func() {
+			expected, err := chudnovsky.Pi(context.Background(), 1100, chudnovsky.Config{})
+			So(err, ShouldBeNil)
+
+			for _, pos := range []int64{1, 13, 500, 1000} {
+				digits, err := DecimalDigitsAt(pos, 25)
+				So(err, ShouldBeNil)
+				for i, d := range digits {
+					So(d, ShouldEqual, expected[pos+int64(i)]-'0')
+				}
+			}
+		})
+		Convey("should reject invalid arguments.", func() {
+			_, err := DecimalDigitsAt(-1, 2)
+			So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+		})
+		Convey("should stop once the context is done.", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := DecimalDigitsAtContext(ctx, 1000, 20)
+			So(err, ShouldEqual, context.Canceled)
+		})
+	})
+}
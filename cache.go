@@ -0,0 +1,220 @@
+package piio
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultCacheBlockSize is the amount of digits per cached
+// block used by Open.
+const DefaultCacheBlockSize = 1 << 16
+
+// CacheStats holds the counters of a CachedChunkSource.
+type CacheStats struct {
+	// Hits is the amount of blocks served from the cache.
+	Hits uint64
+	// Misses is the amount of blocks loaded from the
+	// underlying ChunkSource.
+	Misses uint64
+	// Blocks is the amount of blocks currently cached.
+	Blocks int
+	// Bytes is the amount of memory currently used by
+	// the cached blocks.
+	Bytes int64
+}
+
+// CachedChunkSource is a ChunkSource caching fixed size,
+// aligned blocks of another ChunkSource. If the cache
+// exceeds its byte budget, the least recently used blocks
+// are evicted.
+type CachedChunkSource struct {
+	source    ChunkSource
+	maxSize   int
+	blockSize int
+	budget    int64
+
+	mutex  sync.Mutex
+	blocks map[int64]*list.Element
+	lru    *list.List
+	used   int64
+
+	hits   uint64
+	misses uint64
+}
+
+type cacheEntry struct {
+	block int64
+	chunk Chunk
+	size  int64
+}
+
+// NewCachedChunkSource creates a new CachedChunkSource on
+// top of the given source. Chunks of up to maxSize digits
+// are served from blocks of blockSize digits, which have to
+// be supported by the source. The cache uses up to budget
+// bytes of memory.
+func NewCachedChunkSource(source ChunkSource, maxSize, blockSize int, budget int64) *CachedChunkSource {
+	if blockSize > source.MaximumChunkSize() {
+		blockSize = source.MaximumChunkSize()
+	}
+
+	return &CachedChunkSource{
+		source:    source,
+		maxSize:   maxSize,
+		blockSize: blockSize,
+		budget:    budget,
+		blocks:    make(map[int64]*list.Element),
+		lru:       list.New(),
+	}
+}
+
+// GetChunk returns the requested chunk assembled from the
+// cached blocks, loading missing blocks from the source.
+func (cs *CachedChunkSource) GetChunk(firstIndex int64, size int) (Chunk, error) {
+	return cs.GetChunkContext(context.Background(), firstIndex, size)
+}
+
+// GetChunkContext does the same as GetChunk, but stops
+// loading blocks as soon as the context is done.
+func (cs *CachedChunkSource) GetChunkContext(ctx context.Context, firstIndex int64, size int) (Chunk, error) {
+	err := checkChunkRequest(firstIndex, size, cs.maxSize)
+	if err != nil {
+		return nil, err
+	}
+	if cs.blockSize <= 0 {
+		return nil, errors.New("the block size of the cache is too small")
+	}
+
+	chunk := &UncompressedChunk{
+		FirstDigitIndex: firstIndex,
+		Digits:          make([]byte, 0, size),
+	}
+	lastIndex := firstIndex + int64(size) - 1
+	for block := firstIndex / int64(cs.blockSize); block <= lastIndex/int64(cs.blockSize); block++ {
+		blockChunk, err := cs.getBlock(ctx, block)
+		if errors.Is(err, ErrOutOfRange) && len(chunk.Digits) > 0 {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		from := firstIndex + int64(len(chunk.Digits))
+		to := blockChunk.LastIndex()
+		if to > lastIndex {
+			to = lastIndex
+		}
+		for i := from; i <= to; i++ {
+			d, err := blockChunk.Digit(i)
+			if err != nil {
+				return nil, err
+			}
+			chunk.Digits = append(chunk.Digits, d)
+		}
+
+		if blockChunk.Length() < cs.blockSize {
+			// We reached the end of the source.
+			break
+		}
+	}
+	if len(chunk.Digits) == 0 {
+		return nil, outOfRange(cs, firstIndex, size)
+	}
+
+	return chunk, nil
+}
+
+func (cs *CachedChunkSource) getBlock(ctx context.Context, block int64) (Chunk, error) {
+	cs.mutex.Lock()
+	if e, ok := cs.blocks[block]; ok {
+		cs.lru.MoveToFront(e)
+		cs.mutex.Unlock()
+		atomic.AddUint64(&cs.hits, 1)
+		return e.Value.(*cacheEntry).chunk, nil
+	}
+	cs.mutex.Unlock()
+
+	atomic.AddUint64(&cs.misses, 1)
+	chunk, err := GetChunkContext(ctx, cs.source, block*int64(cs.blockSize), cs.blockSize)
+	if err != nil {
+		return nil, err
+	}
+
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	cs.insert(block, chunk)
+
+	return chunk, nil
+}
+
+// insert adds the block to the cache, evicting the least
+// recently used blocks if necessary. The mutex has to be
+// held by the caller.
+func (cs *CachedChunkSource) insert(block int64, chunk Chunk) {
+	if e, ok := cs.blocks[block]; ok {
+		// Another goroutine loaded the same block.
+		cs.lru.MoveToFront(e)
+		return
+	}
+
+	size := chunkMemorySize(chunk)
+	if size > cs.budget {
+		return
+	}
+	for cs.used+size > cs.budget {
+		e := cs.lru.Back()
+		entry := e.Value.(*cacheEntry)
+		cs.lru.Remove(e)
+		delete(cs.blocks, entry.block)
+		cs.used -= entry.size
+	}
+
+	cs.blocks[block] = cs.lru.PushFront(&cacheEntry{
+		block: block,
+		chunk: chunk,
+		size:  size,
+	})
+	cs.used += size
+}
+
+// chunkMemorySize returns the amount of bytes used to
+// store the digits of the chunk.
+func chunkMemorySize(chnk Chunk) int64 {
+	switch c := chnk.(type) {
+	case *CompressedChunk:
+		return int64(len(c.data))
+	case *DenseChunk:
+		return int64(len(c.data))
+	case *UncompressedChunk:
+		return int64(len(c.Digits))
+	}
+	return int64(chnk.Length())
+}
+
+// AvailableDigits returns the amount of digits available
+// from the underlying source.
+func (cs *CachedChunkSource) AvailableDigits() (int64, error) {
+	return cs.source.AvailableDigits()
+}
+
+// MaximumChunkSize returns the maximum allowed
+// chunk size.
+func (cs *CachedChunkSource) MaximumChunkSize() int {
+	return cs.maxSize
+}
+
+// Stats returns the current counters of the cache.
+func (cs *CachedChunkSource) Stats() CacheStats {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	return CacheStats{
+		Hits:   atomic.LoadUint64(&cs.hits),
+		Misses: atomic.LoadUint64(&cs.misses),
+		Blocks: cs.lru.Len(),
+		Bytes:  cs.used,
+	}
+}
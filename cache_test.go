@@ -0,0 +1,75 @@
+package piio_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/targodan/piio"
+	"github.com/targodan/piio/internal/piiotest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCachedChunkSource(t *testing.T) {
+	Convey("Given a CachedChunkSource", t, func() {
+		source := &piiotest.MemoryChunkSource{Digits: piio.UncompressedPi, MaxSize: 4}
+		cs := piio.NewCachedChunkSource(source, 12, 4, 8)
+
+		Convey("requests spanning blocks should be assembled.", func() {
+			c, err := cs.GetChunk(3, 6)
+			So(err, ShouldBeNil)
+			So(c.FirstIndex(), ShouldEqual, 3)
+			So(c.(*piio.UncompressedChunk).Digits, ShouldResemble, piio.UncompressedPi[3:9])
+			So(cs.Stats().Misses, ShouldEqual, 3)
+			So(cs.Stats().Hits, ShouldEqual, 0)
+		})
+		Convey("cached blocks should not be loaded again.", func() {
+			_, err := cs.GetChunk(0, 4)
+			So(err, ShouldBeNil)
+			c, err := cs.GetChunk(1, 2)
+			So(err, ShouldBeNil)
+			So(c.(*piio.UncompressedChunk).Digits, ShouldResemble, piio.UncompressedPi[1:3])
+			So(source.Calls(), ShouldEqual, 1)
+			So(cs.Stats().Hits, ShouldEqual, 1)
+		})
+		Convey("the least recently used block should be evicted.", func() {
+			_, err := cs.GetChunk(0, 12)
+			So(err, ShouldBeNil)
+			stats := cs.Stats()
+			So(stats.Blocks, ShouldEqual, 2)
+			So(stats.Bytes, ShouldEqual, 8)
+
+			_, err = cs.GetChunk(8, 4)
+			So(err, ShouldBeNil)
+			So(cs.Stats().Hits, ShouldEqual, 1)
+			_, err = cs.GetChunk(0, 4)
+			So(err, ShouldBeNil)
+			So(cs.Stats().Misses, ShouldEqual, 4)
+		})
+		Convey("requests beyond the end should be trimmed.", func() {
+			c, err := cs.GetChunk(10, 12)
+			So(err, ShouldBeNil)
+			So(c.Length(), ShouldEqual, 2)
+
+			_, err = cs.GetChunk(12, 2)
+			So(errors.Is(err, piio.ErrOutOfRange), ShouldBeTrue)
+		})
+		Convey("too large requests should be rejected.", func() {
+			_, err := cs.GetChunk(0, 13)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("concurrent requests should be safe.", func() {
+			wg := &sync.WaitGroup{}
+			for i := 0; i < 16; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					cs.GetChunk(int64(i%10), 2)
+				}(i)
+			}
+			wg.Wait()
+			So(cs.Stats().Bytes, ShouldBeLessThanOrEqualTo, 8)
+		})
+	})
+}
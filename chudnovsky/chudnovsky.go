@@ -0,0 +1,367 @@
+// Package chudnovsky computes digits of pi using the
+// Chudnovsky series and binary splitting.
+package chudnovsky
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+const (
+	// digitsPerTerm is the amount of digits gained with
+	// every term of the series.
+	digitsPerTerm = 14.181647462725477
+	// guardDigits are computed in addition to the requested
+	// digits to make up for rounding errors.
+	guardDigits = 10
+)
+
+var (
+	bigA        = big.NewInt(13591409)
+	bigB        = big.NewInt(545140134)
+	bigC3Over24 = big.NewInt(10939058860032000)
+)
+
+// Config controls how the digits are computed.
+type Config struct {
+	// Workers is the amount of goroutines used. It defaults
+	// to the number of CPUs.
+	Workers int
+	// Segments is the amount of parts the series is split
+	// into. Each segment is computed by a single worker and
+	// is the unit of checkpointing. It defaults to four
+	// segments per worker, or to the amount of segments
+	// stored in the checkpoint directory, so a computation
+	// can be resumed with a different amount of workers.
+	Segments int
+	// CheckpointDir is the directory the computed segments
+	// are stored in. If it is set, segments found in it are
+	// loaded instead of being computed again, so an
+	// interrupted computation can be resumed.
+	CheckpointDir string
+	// Progress is called whenever a segment is done.
+	Progress func(done, total int)
+}
+
+// segment holds the result of the binary splitting of the
+// terms [A, B).
+type segment struct {
+	A, B    int64
+	P, Q, T *big.Int
+}
+
+// Pi returns the first n digits of pi as text, starting
+// with the 3 in front of the decimal point, e.g. "31415".
+func Pi(ctx context.Context, n int, cfg Config) ([]byte, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("expected a positive amount of digits, got %d", n)
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+	if cfg.CheckpointDir != "" {
+		err := checkpointSegments(&cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Segments <= 0 {
+		cfg.Segments = 4 * cfg.Workers
+	}
+
+	terms := int64(float64(n+guardDigits)/digitsPerTerm) + 1
+	if int64(cfg.Segments) > terms {
+		cfg.Segments = int(terms)
+	}
+
+	segments, err := computeSegments(ctx, terms, cfg)
+	if err != nil {
+		return nil, err
+	}
+	s := mergeSegments(segments)
+
+	// pi = 426880 * sqrt(10005) * Q / T, all scaled by 10^precision.
+	precision := int64(n - 1 + guardDigits)
+	one := new(big.Int).Exp(big.NewInt(10), big.NewInt(precision), nil)
+	sqrt := new(big.Int).Mul(one, one)
+	sqrt.Mul(sqrt, big.NewInt(10005))
+	sqrt.Sqrt(sqrt)
+
+	pi := new(big.Int).Mul(s.Q, big.NewInt(426880))
+	pi.Mul(pi, sqrt)
+	pi.Quo(pi, s.T)
+
+	digits := []byte(pi.Text(10))
+	if len(digits) < n {
+		return nil, fmt.Errorf("computed only %d of %d digits", len(digits), n)
+	}
+	return digits[:n], nil
+}
+
+// checkpointSegments sets the amount of segments to the
+// one stored in the checkpoint directory. It stores the
+// configured or default amount if none is stored yet and
+// errors if a different amount is configured.
+func checkpointSegments(cfg *Config) error {
+	stored, err := loadLayout(cfg.CheckpointDir)
+	if err == nil {
+		if cfg.Segments > 0 && cfg.Segments != stored.Segments {
+			return fmt.Errorf("the checkpoint directory \"%s\" holds %d segments, but %d were requested", cfg.CheckpointDir, stored.Segments, cfg.Segments)
+		}
+		cfg.Segments = stored.Segments
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	if cfg.Segments <= 0 {
+		cfg.Segments = 4 * cfg.Workers
+	}
+	return storeGob(cfg.CheckpointDir, layoutFilename(cfg.CheckpointDir), &layout{Segments: cfg.Segments})
+}
+
+// computeSegments computes all segments of the series,
+// distributing them to the workers.
+func computeSegments(ctx context.Context, terms int64, cfg Config) ([]*segment, error) {
+	segments := make([]*segment, cfg.Segments)
+	jobs := make(chan int)
+	errs := make(chan error, cfg.Workers)
+
+	var mutex sync.Mutex
+	done := 0
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wg := &sync.WaitGroup{}
+	for w := 0; w < cfg.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				a := terms * int64(i) / int64(len(segments))
+				b := terms * int64(i+1) / int64(len(segments))
+				s, err := computeSegment(ctx, a, b, cfg.CheckpointDir)
+				if err != nil {
+					errs <- err
+					cancel()
+					return
+				}
+				segments[i] = s
+
+				if cfg.Progress != nil {
+					mutex.Lock()
+					done++
+					cfg.Progress(done, len(segments))
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range segments {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return nil, err
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
+
+// computeSegment computes the segment [a, b) or loads it
+// from the checkpoint directory.
+func computeSegment(ctx context.Context, a, b int64, dir string) (*segment, error) {
+	if dir != "" {
+		s, err := loadSegment(dir, a, b)
+		if err == nil {
+			return s, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s := &segment{A: a, B: b}
+	s.P, s.Q, s.T = split(a, b)
+
+	if dir != "" {
+		err := storeSegment(dir, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// split computes P, Q and T of the terms [a, b) using
+// binary splitting.
+func split(a, b int64) (p, q, t *big.Int) {
+	if b-a == 1 {
+		if a == 0 {
+			p = big.NewInt(1)
+			q = big.NewInt(1)
+		} else {
+			ba := big.NewInt(a)
+			p = big.NewInt(6*a - 5)
+			p.Mul(p, big.NewInt(2*a-1))
+			p.Mul(p, big.NewInt(6*a-1))
+			q = new(big.Int).Mul(ba, ba)
+			q.Mul(q, ba)
+			q.Mul(q, bigC3Over24)
+		}
+		t = new(big.Int).Mul(bigB, big.NewInt(a))
+		t.Add(t, bigA)
+		t.Mul(t, p)
+		if a%2 == 1 {
+			t.Neg(t)
+		}
+		return p, q, t
+	}
+
+	m := (a + b) / 2
+	pam, qam, tam := split(a, m)
+	pmb, qmb, tmb := split(m, b)
+	return merge(pam, qam, tam, pmb, qmb, tmb)
+}
+
+// merge combines the results of two adjacent ranges of terms.
+func merge(pam, qam, tam, pmb, qmb, tmb *big.Int) (p, q, t *big.Int) {
+	p = new(big.Int).Mul(pam, pmb)
+	q = new(big.Int).Mul(qam, qmb)
+	t = new(big.Int).Mul(qmb, tam)
+	t.Add(t, new(big.Int).Mul(pam, tmb))
+	return p, q, t
+}
+
+// mergeSegments combines all segments into one, merging
+// neighbouring pairs in parallel.
+func mergeSegments(segments []*segment) *segment {
+	for len(segments) > 1 {
+		merged := make([]*segment, (len(segments)+1)/2)
+		wg := &sync.WaitGroup{}
+		for i := range merged {
+			if 2*i+1 == len(segments) {
+				merged[i] = segments[2*i]
+				continue
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				l, r := segments[2*i], segments[2*i+1]
+				s := &segment{A: l.A, B: r.B}
+				s.P, s.Q, s.T = merge(l.P, l.Q, l.T, r.P, r.Q, r.T)
+				merged[i] = s
+			}(i)
+		}
+		wg.Wait()
+		segments = merged
+	}
+	return segments[0]
+}
+
+// layout describes how the series is split into segments.
+// It is stored in the checkpoint directory, as the segments
+// can only be reused with the same layout.
+type layout struct {
+	Segments int
+}
+
+func layoutFilename(dir string) string {
+	return filepath.Join(dir, "layout.gob")
+}
+
+func loadLayout(dir string) (*layout, error) {
+	file, err := os.Open(layoutFilename(dir))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	l := &layout{}
+	err = gob.NewDecoder(file).Decode(l)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint \"%s\": %w", file.Name(), err)
+	}
+	if l.Segments <= 0 {
+		return nil, fmt.Errorf("invalid checkpoint \"%s\": expected a positive amount of segments, got %d", file.Name(), l.Segments)
+	}
+	return l, nil
+}
+
+func segmentFilename(dir string, a, b int64) string {
+	return filepath.Join(dir, fmt.Sprintf("segment-%d-%d.gob", a, b))
+}
+
+func loadSegment(dir string, a, b int64) (*segment, error) {
+	file, err := os.Open(segmentFilename(dir, a, b))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	s := &segment{}
+	err = gob.NewDecoder(file).Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint \"%s\": %w", file.Name(), err)
+	}
+	if s.A != a || s.B != b {
+		return nil, fmt.Errorf("invalid checkpoint \"%s\": expected terms [%d, %d), got [%d, %d)", file.Name(), a, b, s.A, s.B)
+	}
+	return s, nil
+}
+
+// storeSegment writes the segment to the checkpoint
+// directory.
+func storeSegment(dir string, s *segment) error {
+	return storeGob(dir, segmentFilename(dir, s.A, s.B), s)
+}
+
+// storeGob writes v to the given file in the checkpoint
+// directory. The file is renamed into place once it is
+// complete, so an interruption never leaves a partial
+// checkpoint behind.
+func storeGob(dir, filename string, v interface{}) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(filename + ".tmp")
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(file).Encode(v)
+	if err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), filename)
+}
@@ -0,0 +1,89 @@
+package chudnovsky
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const piPrefix = "3141592653589793238462643383279502884197169399375105820974944592"
+
+func TestPi(t *testing.T) {
+	Convey("Computing digits of pi", t, func() {
+		Convey("should match the known digits.", func() {
+			digits, err := Pi(context.Background(), len(piPrefix), Config{})
+			So(err, ShouldBeNil)
+			So(string(digits), ShouldEqual, piPrefix)
+		})
+		Convey("should not depend on the splitting.", func() {
+			a, err := Pi(context.Background(), 2000, Config{Workers: 1, Segments: 1})
+			So(err, ShouldBeNil)
+			b, err := Pi(context.Background(), 2000, Config{Workers: 3, Segments: 17})
+			So(err, ShouldBeNil)
+			So(string(a), ShouldEqual, string(b))
+			So(string(a[:len(piPrefix)]), ShouldEqual, piPrefix)
+		})
+		Convey("should error for invalid amounts of digits.", func() {
+			_, err := Pi(context.Background(), 0, Config{})
+			So(err, ShouldNotBeNil)
+		})
+	})
+	Convey("Given a checkpoint directory", t, func() {
+		dir, err := ioutil.TempDir("", "chudnovsky")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		cfg := Config{Workers: 2, Segments: 4, CheckpointDir: dir}
+
+		Convey("a cancelled computation should fail.", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := Pi(ctx, 1000, cfg)
+			So(err, ShouldEqual, context.Canceled)
+		})
+		Convey("a resumed computation should use the stored segments.", func() {
+			expected, err := Pi(context.Background(), 1000, cfg)
+			So(err, ShouldBeNil)
+			files, err := filepath.Glob(filepath.Join(dir, "segment-*.gob"))
+			So(err, ShouldBeNil)
+			So(len(files), ShouldEqual, 4)
+
+			// The removed segment has to be computed again.
+			So(os.Remove(files[0]), ShouldBeNil)
+			cfg.Workers = 1
+			computed := 0
+			cfg.Progress = func(done, total int) {
+				computed = done
+			}
+			digits, err := Pi(context.Background(), 1000, cfg)
+			So(err, ShouldBeNil)
+			So(string(digits), ShouldEqual, string(expected))
+			So(computed, ShouldEqual, 4)
+			_, err = os.Stat(files[0])
+			So(err, ShouldBeNil)
+		})
+		Convey("a resumed computation should keep the amount of segments.", func() {
+			cfg.Segments = 0
+			expected, err := Pi(context.Background(), 1000, cfg)
+			So(err, ShouldBeNil)
+			files, err := filepath.Glob(filepath.Join(dir, "segment-*.gob"))
+			So(err, ShouldBeNil)
+			So(len(files), ShouldEqual, 8)
+
+			cfg.Workers = 3
+			digits, err := Pi(context.Background(), 1000, cfg)
+			So(err, ShouldBeNil)
+			So(string(digits), ShouldEqual, string(expected))
+			resumed, err := filepath.Glob(filepath.Join(dir, "segment-*.gob"))
+			So(err, ShouldBeNil)
+			So(resumed, ShouldResemble, files)
+
+			cfg.Segments = 5
+			_, err = Pi(context.Background(), 1000, cfg)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
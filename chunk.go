@@ -32,64 +32,97 @@ type Chunk interface {
 type CompressedChunk struct {
 	firstIndex int64
 	data       []byte
+	// lowStart is set if the first digit is stored in
+	// the low nibble of the first byte.
+	lowStart bool
+	// highEnd is set if the last digit is stored in the
+	// high nibble of the last byte.
+	highEnd bool
 }
 
 // ReadCompressedChunk reads a certain chunk defined by
 // the index of the first requested digit of pi and the
 // amount of digits requested.
-// Both the first index and the size have to be positive and
-// even. The given file has to be seekable.
+// Both the first index and the size have to be positive.
+// The given file has to be seekable. If the file starts
+// with a Header it is validated and the chunk is limited to
+// the amount of digits it states.
 //
 // The expected file format is as follows.
 // Binary digits, each digit 4 bits wide with the lower index
-// digit in the higher nibble of each byte.
+// digit in the higher nibble of each byte. If the amount of
+// digits is odd, the low nibble of the last byte is padded.
 func ReadCompressedChunk(input io.ReadSeeker, firstIndex int64, size int) (Chunk, error) {
-	if firstIndex < 0 || firstIndex%2 != 0 {
-		return nil, errors.New("only positive even first indexes are supported")
+	err := checkChunkArguments(firstIndex, size)
+	if err != nil {
+		return nil, err
 	}
-	if size <= 0 || size%2 != 0 {
-		return nil, errors.New("only positive even sizes are supported")
+	input, h, err := dataSection(input, FileFormatCompressed)
+	if err != nil {
+		return nil, err
 	}
-	_, err := input.Seek(firstIndex/2, 0)
+	size, err = limitSize(h, firstIndex, size)
 	if err != nil {
 		return nil, err
 	}
-
-	chunk := &CompressedChunk{
-		firstIndex: firstIndex,
-		data:       make([]byte, size/2),
+	_, err = input.Seek(firstIndex/2, 0)
+	if err != nil {
+		return nil, err
 	}
 
-	size, err = input.Read(chunk.data)
+	data := make([]byte, (int(firstIndex%2)+size+1)/2)
+	n, err := input.Read(data)
 	if err != nil {
 		return nil, err
 	}
 
 	// Trim in case we requested more than the file can give us.
-	chunk.data = chunk.data[:size]
+	chnk := compressedChunkAt(data[:n], firstIndex, size)
+	err = validateChunk(chnk)
+	if err != nil {
+		return nil, err
+	}
+	return chnk, nil
+}
 
-	return chunk, nil
+// compressedChunkAt creates a CompressedChunk of up to size
+// digits starting at firstIndex. The data has to start with
+// the byte containing the first digit.
+func compressedChunkAt(data []byte, firstIndex int64, size int) *CompressedChunk {
+	skip := int(firstIndex % 2)
+	if available := len(data)*2 - skip; available < size {
+		size = available
+	}
+	end := (skip + size + 1) / 2
+	return &CompressedChunk{
+		firstIndex: firstIndex,
+		data:       data[:end:end],
+		lowStart:   skip == 1,
+		highEnd:    (skip+size)%2 == 1,
+	}
 }
 
-// Compress compresses an uncompressed chunk.
+// Compress compresses any chunk into a CompressedChunk.
+// The first digit of the result is always stored in the
+// high nibble of the first byte.
 func Compress(chnk Chunk) Chunk {
-	if chnk.IsCompressed() {
+	if _, ok := chnk.(*CompressedChunk); ok {
 		return chnk
 	}
 
-	c, ok := chnk.(*UncompressedChunk)
-	if !ok {
-		panic("can only uncompress CompressedChunks")
-	}
+	c := AsUncompressedChunk(chnk)
 
 	chunk := &CompressedChunk{
 		firstIndex: c.FirstIndex(),
-		data:       make([]byte, len(c.Digits)/2),
+		data:       make([]byte, (len(c.Digits)+1)/2),
 	}
 	for i := 0; i < len(chunk.data); i++ {
 		chunk.data[i] = c.Digits[i*2] << 4
-		chunk.data[i] |= c.Digits[i*2+1]
+		if i*2+1 < len(c.Digits) {
+			chunk.data[i] |= c.Digits[i*2+1]
+		}
 	}
+	chunk.highEnd = len(c.Digits)%2 == 1
 	return chunk
 }
 
@@ -99,8 +132,12 @@ func (c *CompressedChunk) IsCompressed() bool {
 }
 
 func (c *CompressedChunk) digitIndexToDataIndex(index int64) (ind int, isHighNibble bool) {
-	ind = int((index - c.firstIndex) / 2)
-	isHighNibble = (index-c.firstIndex)%2 == 0
+	nibble := index - c.firstIndex
+	if c.lowStart {
+		nibble++
+	}
+	ind = int(nibble / 2)
+	isHighNibble = nibble%2 == 0
 	return
 }
 
@@ -113,7 +150,14 @@ func (c *CompressedChunk) FirstIndex() int64 {
 // Length returns the amount of digits contained in
 // this chunk.
 func (c *CompressedChunk) Length() int {
-	return len(c.data) * 2
+	length := len(c.data) * 2
+	if c.lowStart {
+		length--
+	}
+	if c.highEnd {
+		length--
+	}
+	return length
 }
 
 // LastIndex returns the index of the last digit of pi
@@ -123,10 +167,11 @@ func (c *CompressedChunk) LastIndex() int64 {
 }
 
 // Digit returns the index-th digit of pi. It errors if
-// the requested digit is not contained in this chunk.
+// the requested digit is not contained in this chunk or
+// if the stored nibble is not a valid digit.
 func (c *CompressedChunk) Digit(index int64) (byte, error) {
-	if index < c.firstIndex || int((index-c.firstIndex)/2) >= len(c.data) {
-		return 255, errors.New("index out of range")
+	if index < c.firstIndex || index > c.LastIndex() {
+		return 255, chunkOutOfRange(c, index)
 	}
 	ind, isHighNibble := c.digitIndexToDataIndex(index)
 
@@ -135,6 +180,12 @@ func (c *CompressedChunk) Digit(index int64) (byte, error) {
 		digit = digit >> 4
 	}
 	digit = (digit & 0x0F)
+	if digit > 9 {
+		return 255, &CorruptDataError{
+			Index:  index,
+			Reason: "invalid digit",
+		}
+	}
 
 	return digit, nil
 }
@@ -149,26 +200,49 @@ type UncompressedChunk struct {
 // ReadTextChunk reads a certain chunk defined by
 // the index of the first requested digit of pi and the
 // amount of digits requested from a text based input.
-// Both the first index and the size have to be positive and
-// even. The given file has to be seekable.
+// Both the first index and the size have to be positive.
+// The given file has to be seekable. If the file starts
+// with a Header it is validated and the chunk is limited to
+// the amount of digits it states.
 //
-// The expected file format is one character for each digit
-// no decimal point. So the file should start with `314`...
+// The expected file format is one character for each digit.
+// A decimal point directly following the integer digits is
+// skipped, so the file may start with `314` or `3.14`...
 func ReadTextChunk(input io.ReadSeeker, firstIndex int64, size int) (Chunk, error) {
-	if firstIndex < 0 || firstIndex%2 != 0 {
-		return nil, errors.New("only positive even first indexes are supported")
+	err := checkChunkArguments(firstIndex, size)
+	if err != nil {
+		return nil, err
+	}
+	input, h, err := dataSection(input, FileFormatText)
+	if err != nil {
+		return nil, err
+	}
+	size, err = limitSize(h, firstIndex, size)
+	if err != nil {
+		return nil, err
+	}
+	point, err := textPointPosition(input)
+	if err != nil {
+		return nil, err
 	}
-	if size <= 0 || size%2 != 0 {
-		return nil, errors.New("only positive even sizes are supported")
+
+	// Skip over the decimal point, the filter below drops it
+	// if it is part of the chunk.
+	offset := firstIndex
+	length := size
+	if point >= 0 && firstIndex >= point {
+		offset++
+	} else if point >= 0 && firstIndex+int64(size) > point {
+		length++
 	}
-	_, err := input.Seek(firstIndex, 0)
+	_, err = input.Seek(offset, 0)
 	if err != nil {
 		return nil, err
 	}
 
 	chunk := &UncompressedChunk{
 		FirstDigitIndex: firstIndex,
-		Digits:          make([]byte, size),
+		Digits:          make([]byte, length),
 	}
 
 	size, err = input.Read(chunk.Digits)
@@ -192,24 +266,94 @@ func ReadTextChunk(input io.ReadSeeker, firstIndex int64, size int) (Chunk, erro
 	return chunk, nil
 }
 
+// textPointPosition returns the position of the decimal
+// point following the integer digits at the start of a text
+// file, e.g. 1 for "3.1415". It returns -1 if there is none.
+func textPointPosition(input io.ReadSeeker) (int64, error) {
+	_, err := input.Seek(0, 0)
+	if err != nil {
+		return -1, err
+	}
+	start := make([]byte, 32)
+	n, err := input.Read(start)
+	if err != nil && err != io.EOF {
+		return -1, err
+	}
+	for i := 0; i < n; i++ {
+		if start[i] == '.' && i > 0 {
+			return int64(i), nil
+		}
+		if start[i] < '0' || '9' < start[i] {
+			break
+		}
+	}
+	return -1, nil
+}
+
+// textTrailingSpace returns the amount of whitespace
+// characters at the end of a text file of the given size,
+// like a final newline.
+func textTrailingSpace(input io.ReadSeeker, size int64) (int64, error) {
+	buf := make([]byte, 32)
+	var space int64
+	for space < size {
+		n := int64(len(buf))
+		if n > size-space {
+			n = size - space
+		}
+		_, err := input.Seek(size-space-n, 0)
+		if err != nil {
+			return 0, err
+		}
+		_, err = io.ReadFull(input, buf[:n])
+		if err != nil {
+			return 0, err
+		}
+		for i := n - 1; i >= 0; i-- {
+			switch buf[i] {
+			case '\n', '\r', ' ', '\t':
+				space++
+			default:
+				return space, nil
+			}
+		}
+	}
+	return space, nil
+}
+
 // Decompress decompresses a compressed chunk.
+// Invalid digits are not detected. The readers and
+// ChunkSources validate every chunk they return, so only
+// chunks created by other means need to be checked via
+// Digit.
 func Decompress(chnk Chunk) Chunk {
 	if !chnk.IsCompressed() {
 		return chnk
 	}
 
+	if d, ok := chnk.(*DenseChunk); ok {
+		return d.decompress()
+	}
+
 	c, ok := chnk.(*CompressedChunk)
 	if !ok {
-		panic("can only uncompress CompressedChunks")
+		panic("can only uncompress CompressedChunks and DenseChunks")
 	}
+	return c.decompress()
+}
 
+func (c *CompressedChunk) decompress() *UncompressedChunk {
 	chunk := &UncompressedChunk{
 		FirstDigitIndex: c.firstIndex,
-		Digits:          make([]byte, len(c.data)*2),
+		Digits:          make([]byte, c.Length()),
 	}
-	for i := 0; i < len(c.data); i++ {
-		chunk.Digits[i*2] = (c.data[i] >> 4) & 0x0F
-		chunk.Digits[i*2+1] = (c.data[i] & 0x0F)
+	for i := range chunk.Digits {
+		ind, isHighNibble := c.digitIndexToDataIndex(c.firstIndex + int64(i))
+		if isHighNibble {
+			chunk.Digits[i] = (c.data[ind] >> 4) & 0x0F
+		} else {
+			chunk.Digits[i] = (c.data[ind] & 0x0F)
+		}
 	}
 	return chunk
 }
@@ -242,11 +386,39 @@ func (c *UncompressedChunk) LastIndex() int64 {
 func (c *UncompressedChunk) Digit(index int64) (byte, error) {
 	ind := int(index - c.FirstDigitIndex)
 	if index < c.FirstDigitIndex || ind >= len(c.Digits) {
-		return 255, errors.New("index out of range")
+		return 255, chunkOutOfRange(c, index)
 	}
 	return c.Digits[ind], nil
 }
 
+// validateChunk returns a CorruptDataError for the first
+// digit of the chunk that is not valid.
+func validateChunk(chnk Chunk) error {
+	for i := chnk.FirstIndex(); i <= chnk.LastIndex(); i++ {
+		_, err := chnk.Digit(i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// limitSize limits the size of a chunk to the amount of
+// digits stated in the header. It returns io.EOF if the
+// first index lies beyond the last digit.
+func limitSize(h *Header, firstIndex int64, size int) (int, error) {
+	if h == nil {
+		return size, nil
+	}
+	if firstIndex >= h.DigitCount {
+		return 0, io.EOF
+	}
+	if int64(size) > h.DigitCount-firstIndex {
+		size = int(h.DigitCount - firstIndex)
+	}
+	return size, nil
+}
+
 // AsUncompressedChunk returns the given Chunk as an
 // UncompressedChunk. Panics if wrong type is given.
 func AsUncompressedChunk(chnk Chunk) *UncompressedChunk {
@@ -258,26 +430,43 @@ func AsUncompressedChunk(chnk Chunk) *UncompressedChunk {
 	return c
 }
 
+// WriteChunk writes the given chunk to w using the given
+// file format.
+// The chunk is always written as if it started a new file.
+// So when writing consecutive chunks every chunk but the
+// last has to contain an even amount of digits in
+// FileFormatCompressed and a multiple of DenseBlockDigits
+// digits in FileFormatDense.
 func WriteChunk(chnk Chunk, format FileFormat, w io.Writer) error {
 	if format == FileFormatCompressed {
 		chnk = Compress(chnk)
 		c, ok := chnk.(*CompressedChunk)
 		if !ok {
-			return errors.New("unknown Chunk type")
+			return &InvalidArgumentError{"unknown Chunk type"}
 		}
 		return writeCompressedChunk(c, w)
 	} else if format == FileFormatText {
 		chnk = Decompress(chnk)
 		c, ok := chnk.(*UncompressedChunk)
 		if !ok {
-			return errors.New("unknown Chunk type")
+			return &InvalidArgumentError{"unknown Chunk type"}
 		}
 		return writeUncompressedChunkText(c, w)
+	} else if format == FileFormatDense {
+		chnk = CompressDense(chnk)
+		c, ok := chnk.(*DenseChunk)
+		if !ok {
+			return &InvalidArgumentError{"unknown Chunk type"}
+		}
+		return writeDenseChunk(c, w)
 	}
-	return errors.New("unknown file format")
+	return errUnknownFileFormat
 }
 
 func writeCompressedChunk(chnk *CompressedChunk, w io.Writer) error {
+	if chnk.lowStart {
+		chnk = Compress(chnk.decompress()).(*CompressedChunk)
+	}
 	n, err := w.Write(chnk.data)
 	if err != nil {
 		return err
@@ -1,6 +1,8 @@
 package piio
 
 import (
+	"bytes"
+	"errors"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -245,3 +247,100 @@ func TestUncompress(t *testing.T) {
 		})
 	})
 }
+
+func TestCompressedLowNibbleStart(t *testing.T) {
+	Convey("Given a CompressedChunk starting on a low nibble", t, func() {
+		chnk := &CompressedChunk{
+			firstIndex: 3,
+			data:       compressedPi[1:4],
+			lowStart:   true,
+			highEnd:    true,
+		}
+		Convey("the length should exclude the unused nibbles.", func() {
+			So(chnk.Length(), ShouldEqual, 4)
+			So(chnk.LastIndex(), ShouldEqual, 6)
+		})
+		Convey("Digits() should work.", func() {
+			_, err := chnk.Digit(2)
+			So(err, ShouldNotBeNil)
+			_, err = chnk.Digit(7)
+			So(err, ShouldNotBeNil)
+			for i := int64(3); i <= 6; i++ {
+				b, err := chnk.Digit(i)
+				So(err, ShouldBeNil)
+				So(b, ShouldEqual, uncompressedPi[i])
+			}
+		})
+		Convey("decompressing should work.", func() {
+			c := Decompress(chnk).(*UncompressedChunk)
+			So(c.FirstDigitIndex, ShouldEqual, 3)
+			So(c.Digits, ShouldResemble, uncompressedPi[3:7])
+		})
+		Convey("writing should realign the digits.", func() {
+			buf := &bytes.Buffer{}
+			So(WriteChunk(chnk, FileFormatCompressed, buf), ShouldBeNil)
+			So(buf.Bytes(), ShouldResemble, []byte{0x15, 0x92})
+		})
+	})
+}
+
+func TestCompressedInvalidNibble(t *testing.T) {
+	Convey("Given a CompressedChunk with an invalid nibble", t, func() {
+		chnk := &CompressedChunk{
+			firstIndex: 0,
+			data:       []byte{0x31, 0x4C},
+		}
+		Convey("Digits() should error on the invalid nibble only.", func() {
+			b, err := chnk.Digit(2)
+			So(err, ShouldBeNil)
+			So(b, ShouldEqual, 4)
+			_, err = chnk.Digit(3)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("reading it should return a CorruptDataError.", func() {
+			_, err := ReadCompressedChunk(bytes.NewReader(chnk.data), 1, 3)
+			var dataErr *CorruptDataError
+			So(errors.As(err, &dataErr), ShouldBeTrue)
+			So(dataErr.Index, ShouldEqual, 3)
+
+			_, err = ReadCompressedChunk(bytes.NewReader(chnk.data), 0, 3)
+			So(err, ShouldBeNil)
+		})
+	})
+}
+
+func TestReadOddChunks(t *testing.T) {
+	Convey("Given a compressed file", t, func() {
+		file := bytes.NewReader(compressedPi)
+		Convey("reading at odd indexes should work.", func() {
+			c, err := ReadCompressedChunk(file, 3, 5)
+			So(err, ShouldBeNil)
+			So(c.FirstIndex(), ShouldEqual, 3)
+			So(Decompress(c).(*UncompressedChunk).Digits, ShouldResemble, uncompressedPi[3:8])
+		})
+		Convey("reading beyond the end should trim the chunk.", func() {
+			c, err := ReadCompressedChunk(file, 9, 5)
+			So(err, ShouldBeNil)
+			So(Decompress(c).(*UncompressedChunk).Digits, ShouldResemble, uncompressedPi[9:])
+		})
+	})
+	Convey("Given a text file", t, func() {
+		file := bytes.NewReader([]byte(textPi))
+		Convey("reading at odd indexes should work.", func() {
+			c, err := ReadTextChunk(file, 1, 3)
+			So(err, ShouldBeNil)
+			So(c.(*UncompressedChunk).Digits, ShouldResemble, uncompressedPi[1:4])
+		})
+	})
+	Convey("Given an odd UncompressedChunk", t, func() {
+		chnk := &UncompressedChunk{
+			FirstDigitIndex: 0,
+			Digits:          uncompressedPi[:3],
+		}
+		Convey("compressing should keep the exact length.", func() {
+			c := Compress(chnk)
+			So(c.Length(), ShouldEqual, 3)
+			So(Decompress(c).(*UncompressedChunk).Digits, ShouldResemble, uncompressedPi[:3])
+		})
+	})
+}
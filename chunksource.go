@@ -1,8 +1,9 @@
 package piio
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"io"
 	"os"
 )
 
@@ -11,11 +12,44 @@ type FileFormat int
 
 const (
 	// FileFormatCompressed represents a compressed binary format. See ReadCompressedChunkFile.
-	FileFormatCompressed = iota
+	FileFormatCompressed FileFormat = iota
 	// FileFormatText represents a text format. See ReadChunkFromTextfile.
 	FileFormatText
+	// FileFormatDense represents a densely packed binary format. See ReadDenseChunk.
+	FileFormatDense
+	// FileFormatYCD represents the .ycd files written by y-cruncher.
+	// It can only be read, see NewYCDChunkSource.
+	FileFormatYCD
 )
 
+var fileFormatNames = map[FileFormat]string{
+	FileFormatCompressed: "compressed",
+	FileFormatText:       "text",
+	FileFormatDense:      "dense",
+	FileFormatYCD:        "ycd",
+}
+
+// ParseFileFormat returns the FileFormat with the given name.
+// See FileFormat.String for the names.
+func ParseFileFormat(name string) (FileFormat, error) {
+	for format, n := range fileFormatNames {
+		if n == name {
+			return format, nil
+		}
+	}
+	return 0, &UnsupportedFormatError{fmt.Sprintf("unknown file format \"%s\"", name)}
+}
+
+// String returns the name of the file format, i.e. one of
+// "compressed", "text", "dense" or "ycd".
+func (f FileFormat) String() string {
+	name, ok := fileFormatNames[f]
+	if !ok {
+		return fmt.Sprintf("FileFormat(%d)", int(f))
+	}
+	return name
+}
+
 // ChunkSource represents a source of chunks.
 // It has to be thread safe.
 type ChunkSource interface {
@@ -45,8 +79,15 @@ func NewUncachedChunkSource(filename string, fileFormat FileFormat, maxSize int)
 }
 
 func (cs *uncachedChunkSource) GetChunk(firstIndex int64, size int) (Chunk, error) {
-	if size > cs.maxSize {
-		return nil, fmt.Errorf("requested chunk of size %d but only supporting chunks of size up to %d", size, cs.maxSize)
+	return cs.GetChunkContext(context.Background(), firstIndex, size)
+}
+
+// GetChunkContext stops reading the file as soon as the
+// context is done.
+func (cs *uncachedChunkSource) GetChunkContext(ctx context.Context, firstIndex int64, size int) (Chunk, error) {
+	err := checkChunkRequest(firstIndex, size, cs.maxSize)
+	if err != nil {
+		return nil, err
 	}
 
 	file, err := os.Open(cs.filename)
@@ -54,24 +95,72 @@ func (cs *uncachedChunkSource) GetChunk(firstIndex int64, size int) (Chunk, erro
 		return nil, err
 	}
 	defer file.Close()
+	input := &contextReadSeeker{ctx, file}
 
+	var chnk Chunk
 	switch cs.fileFormat {
 	case FileFormatCompressed:
-		return ReadCompressedChunk(file, firstIndex, size)
+		chnk, err = ReadCompressedChunk(input, firstIndex, size)
 
 	case FileFormatText:
-		return ReadTextChunk(file, firstIndex, size)
+		chnk, err = ReadTextChunk(input, firstIndex, size)
+
+	case FileFormatDense:
+		chnk, err = ReadDenseChunk(input, firstIndex, size)
+
+	default:
+		return nil, errUnknownFileFormat
 	}
 
-	return nil, errors.New("unknown file format")
+	if err == io.EOF {
+		return nil, outOfRange(cs, firstIndex, size)
+	}
+	return chnk, err
 }
 
 func (cs *uncachedChunkSource) AvailableDigits() (int64, error) {
-	fi, err := os.Stat(cs.filename)
+	file, err := os.Open(cs.filename)
 	if err != nil {
 		return 0, err
 	}
-	return fi.Size() * int64(2), nil
+	defer file.Close()
+
+	h, err := readHeader(file)
+	if err != nil {
+		return 0, err
+	}
+	if h != nil {
+		return h.DigitCount, nil
+	}
+
+	// Without a header we can only guess from the file size.
+	fi, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	switch cs.fileFormat {
+	case FileFormatCompressed:
+		return fi.Size() * 2, nil
+
+	case FileFormatText:
+		point, err := textPointPosition(file)
+		if err != nil {
+			return 0, err
+		}
+		space, err := textTrailingSpace(file, fi.Size())
+		if err != nil {
+			return 0, err
+		}
+		digits := fi.Size() - space
+		if point >= 0 {
+			digits--
+		}
+		return digits, nil
+
+	case FileFormatDense:
+		return fi.Size() * 8 / denseGroupBits * denseGroupDigits, nil
+	}
+	return 0, errUnknownFileFormat
 }
 
 func (cs *uncachedChunkSource) MaximumChunkSize() int {
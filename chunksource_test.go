@@ -0,0 +1,33 @@
+package piio_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/targodan/piio"
+	"github.com/targodan/piio/internal/piiotest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestChunkSourceReader(t *testing.T) {
+	Convey("Given a ChunkSource", t, func() {
+		cs := &piiotest.MemoryChunkSource{Digits: piio.UncompressedPi, MaxSize: 5}
+
+		Convey("reading all digits should work.", func() {
+			read, err := ioutil.ReadAll(piio.NewChunkSourceReader(cs, 0, -1))
+			So(err, ShouldBeNil)
+			So(string(read), ShouldEqual, piio.TextPi)
+		})
+		Convey("reading a range should work.", func() {
+			read, err := ioutil.ReadAll(piio.NewChunkSourceReader(cs, 3, 7))
+			So(err, ShouldBeNil)
+			So(string(read), ShouldEqual, piio.TextPi[3:10])
+		})
+		Convey("reading beyond the end should fail.", func() {
+			_, err := ioutil.ReadAll(piio.NewChunkSourceReader(cs, 8, 7))
+			So(errors.Is(err, piio.ErrOutOfRange), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,180 @@
+package piio
+
+import (
+	"context"
+	"time"
+)
+
+type computedChunkSource struct {
+	maxSize int
+	limit   int64
+	timeout time.Duration
+}
+
+// NewComputedChunkSource creates a ChunkSource computing
+// the digits of pi on demand, see DecimalDigitsAt. Only the
+// digits in front of limit are available. If timeout is
+// positive, computations taking longer are aborted with
+// context.DeadlineExceeded.
+func NewComputedChunkSource(maxSize int, limit int64, timeout time.Duration) ChunkSourceContext {
+	return &computedChunkSource{
+		maxSize: maxSize,
+		limit:   limit,
+		timeout: timeout,
+	}
+}
+
+func (cs *computedChunkSource) GetChunk(firstIndex int64, size int) (Chunk, error) {
+	return cs.GetChunkContext(context.Background(), firstIndex, size)
+}
+
+func (cs *computedChunkSource) GetChunkContext(ctx context.Context, firstIndex int64, size int) (Chunk, error) {
+	err := checkChunkRequest(firstIndex, size, cs.maxSize)
+	if err != nil {
+		return nil, err
+	}
+	if firstIndex >= cs.limit {
+		return nil, outOfRange(cs, firstIndex, size)
+	}
+	if int64(size) > cs.limit-firstIndex {
+		size = int(cs.limit - firstIndex)
+	}
+
+	if cs.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cs.timeout)
+		defer cancel()
+	}
+	digits, err := DecimalDigitsAtContext(ctx, firstIndex, size)
+	if err != nil {
+		return nil, err
+	}
+	return &UncompressedChunk{
+		FirstDigitIndex: firstIndex,
+		Digits:          digits,
+	}, nil
+}
+
+func (cs *computedChunkSource) AvailableDigits() (int64, error) {
+	return cs.limit, nil
+}
+
+func (cs *computedChunkSource) MaximumChunkSize() int {
+	return cs.maxSize
+}
+
+// FallbackChunkSource serves the digits of a stored source
+// and falls back to another source, usually computing the
+// digits, for the digits past the end of the stored ones.
+type FallbackChunkSource struct {
+	stored   ChunkSourceContext
+	fallback ChunkSourceContext
+}
+
+// NewFallbackChunkSource creates a new FallbackChunkSource.
+// The maximum chunk size is the one of the stored source,
+// the limits of the fallback apply to the part of a chunk
+// served by it.
+func NewFallbackChunkSource(stored, fallback ChunkSource) *FallbackChunkSource {
+	return &FallbackChunkSource{
+		stored:   WithContext(stored),
+		fallback: WithContext(fallback),
+	}
+}
+
+// GetChunk returns the requested chunk.
+func (cs *FallbackChunkSource) GetChunk(firstIndex int64, size int) (Chunk, error) {
+	return cs.GetChunkContext(context.Background(), firstIndex, size)
+}
+
+// GetChunkContext returns the requested chunk, combining
+// the digits of both sources if necessary.
+func (cs *FallbackChunkSource) GetChunkContext(ctx context.Context, firstIndex int64, size int) (Chunk, error) {
+	err := checkChunkRequest(firstIndex, size, cs.MaximumChunkSize())
+	if err != nil {
+		return nil, err
+	}
+	stored, err := cs.stored.AvailableDigits()
+	if err != nil {
+		return nil, err
+	}
+
+	if firstIndex+int64(size) <= stored {
+		return cs.stored.GetChunkContext(ctx, firstIndex, size)
+	}
+	if firstIndex >= stored {
+		return cs.fallback.GetChunkContext(ctx, firstIndex, size)
+	}
+
+	head, err := cs.stored.GetChunkContext(ctx, firstIndex, int(stored-firstIndex))
+	if err != nil {
+		return nil, err
+	}
+	tail, err := cs.fallback.GetChunkContext(ctx, stored, size-head.Length())
+	if err != nil {
+		return nil, err
+	}
+	chunk := &UncompressedChunk{
+		FirstDigitIndex: firstIndex,
+		Digits:          make([]byte, 0, head.Length()+tail.Length()),
+	}
+	chunk.Digits = append(chunk.Digits, AsUncompressedChunk(head).Digits...)
+	chunk.Digits = append(chunk.Digits, AsUncompressedChunk(tail).Digits...)
+	return chunk, nil
+}
+
+// Stored returns the source of the stored digits.
+func (cs *FallbackChunkSource) Stored() ChunkSource {
+	return cs.stored
+}
+
+// Fallback returns the source of the digits past the end of
+// the stored ones.
+func (cs *FallbackChunkSource) Fallback() ChunkSource {
+	return cs.fallback
+}
+
+// AvailableDigits returns the amount of digits available
+// from either source.
+func (cs *FallbackChunkSource) AvailableDigits() (int64, error) {
+	ranges, err := cs.Ranges()
+	if err != nil {
+		return 0, err
+	}
+	return ranges.Fallback.FirstIndex + ranges.Fallback.Size, nil
+}
+
+// MaximumChunkSize returns the maximum allowed
+// chunk size.
+func (cs *FallbackChunkSource) MaximumChunkSize() int {
+	return cs.stored.MaximumChunkSize()
+}
+
+// FallbackRanges describes which digits of a
+// FallbackChunkSource are served by which source.
+type FallbackRanges struct {
+	Stored   Range
+	Fallback Range
+}
+
+// Ranges returns the digits served by the stored source and
+// the ones served by the fallback.
+func (cs *FallbackChunkSource) Ranges() (FallbackRanges, error) {
+	stored, err := cs.stored.AvailableDigits()
+	if err != nil {
+		return FallbackRanges{}, err
+	}
+	fallback, err := cs.fallback.AvailableDigits()
+	if err != nil {
+		return FallbackRanges{}, err
+	}
+
+	ranges := FallbackRanges{
+		Stored:   Range{FirstIndex: 0, Size: stored},
+		Fallback: Range{FirstIndex: stored, Size: fallback - stored},
+	}
+	if ranges.Fallback.Size < 0 {
+		ranges.Fallback.Size = 0
+	}
+	return ranges, nil
+}
@@ -0,0 +1,63 @@
+package piio_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/targodan/piio"
+	"github.com/targodan/piio/internal/piiotest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFallbackChunkSource(t *testing.T) {
+	Convey("Given a stored source falling back to computed digits", t, func() {
+		stored := &piiotest.MemoryChunkSource{Digits: piio.UncompressedPi[:8], MaxSize: 10}
+		cs := piio.NewFallbackChunkSource(stored, piio.NewComputedChunkSource(4, 12, time.Minute))
+
+		Convey("the ranges should be reported.", func() {
+			ranges, err := cs.Ranges()
+			So(err, ShouldBeNil)
+			So(ranges.Stored, ShouldResemble, piio.Range{FirstIndex: 0, Size: 8})
+			So(ranges.Fallback, ShouldResemble, piio.Range{FirstIndex: 8, Size: 4})
+
+			avail, err := cs.AvailableDigits()
+			So(err, ShouldBeNil)
+			So(avail, ShouldEqual, 12)
+		})
+		Convey("stored digits should not be computed.", func() {
+			c, err := cs.GetChunk(0, 8)
+			So(err, ShouldBeNil)
+			So(piio.AsUncompressedChunk(c).Digits, ShouldResemble, piio.UncompressedPi[:8])
+			So(stored.Calls(), ShouldEqual, 1)
+		})
+		Convey("digits past the stored ones should be computed.", func() {
+			c, err := cs.GetChunk(9, 3)
+			So(err, ShouldBeNil)
+			So(c.FirstIndex(), ShouldEqual, 9)
+			So(piio.DigitString(piio.AsUncompressedChunk(c).Digits), ShouldEqual, piio.PiDecimals[8:11])
+			So(stored.Calls(), ShouldEqual, 0)
+		})
+		Convey("chunks spanning both sources should be combined.", func() {
+			c, err := cs.GetChunk(5, 7)
+			So(err, ShouldBeNil)
+			So(piio.DigitString(piio.AsUncompressedChunk(c).Digits), ShouldEqual, piio.PiDecimals[4:11])
+		})
+		Convey("the limits of the computed source should apply.", func() {
+			_, err := cs.GetChunk(8, 5)
+			So(errors.Is(err, piio.ErrChunkTooLarge), ShouldBeTrue)
+			_, err = cs.GetChunk(12, 2)
+			So(errors.Is(err, piio.ErrOutOfRange), ShouldBeTrue)
+		})
+	})
+	Convey("Given a computed source with a short timeout", t, func() {
+		cs := piio.NewComputedChunkSource(4, 1000000, time.Millisecond)
+
+		Convey("slow computations should be aborted.", func() {
+			_, err := cs.GetChunkContext(context.Background(), 100000, 4)
+			So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,82 @@
+package piio
+
+import (
+	"context"
+	"io"
+)
+
+// ChunkSourceContext is a ChunkSource whose reads can be
+// cancelled via a context.
+type ChunkSourceContext interface {
+	ChunkSource
+	// GetChunkContext returns the requested chunk. It stops
+	// and returns the error of the context as soon as the
+	// context is done.
+	GetChunkContext(ctx context.Context, firstIndex int64, size int) (Chunk, error)
+}
+
+type contextChunkSource struct {
+	ChunkSource
+}
+
+// WithContext returns the given source as a ChunkSourceContext.
+// Sources not implementing it are wrapped, so that a call
+// to GetChunkContext returns as soon as the context is done.
+// The wrapped GetChunk keeps running in the background in
+// that case, as it cannot be interrupted.
+func WithContext(cs ChunkSource) ChunkSourceContext {
+	if csc, ok := cs.(ChunkSourceContext); ok {
+		return csc
+	}
+	return &contextChunkSource{cs}
+}
+
+// GetChunkContext gets the requested chunk from the source
+// respecting the given context. See WithContext.
+func GetChunkContext(ctx context.Context, cs ChunkSource, firstIndex int64, size int) (Chunk, error) {
+	return WithContext(cs).GetChunkContext(ctx, firstIndex, size)
+}
+
+func (cs *contextChunkSource) GetChunkContext(ctx context.Context, firstIndex int64, size int) (Chunk, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		chunk Chunk
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		chunk, err := cs.GetChunk(firstIndex, size)
+		done <- result{chunk, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.chunk, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// contextReadSeeker fails all reads and seeks once the
+// context is done.
+type contextReadSeeker struct {
+	ctx context.Context
+	io.ReadSeeker
+}
+
+func (r *contextReadSeeker) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.ReadSeeker.Read(p)
+}
+
+func (r *contextReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.ReadSeeker.Seek(offset, whence)
+}
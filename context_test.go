@@ -0,0 +1,77 @@
+package piio_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/targodan/piio"
+	"github.com/targodan/piio/internal/piiotest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// blockingChunkSource blocks every request until it
+// is released.
+type blockingChunkSource struct {
+	piiotest.MemoryChunkSource
+	release chan struct{}
+}
+
+func (cs *blockingChunkSource) GetChunk(firstIndex int64, size int) (piio.Chunk, error) {
+	<-cs.release
+	return cs.MemoryChunkSource.GetChunk(firstIndex, size)
+}
+
+func TestChunkSourceContext(t *testing.T) {
+	Convey("Given a ChunkSource without context support", t, func() {
+		source := &blockingChunkSource{
+			MemoryChunkSource: piiotest.MemoryChunkSource{Digits: piio.UncompressedPi, MaxSize: 8},
+			release:           make(chan struct{}),
+		}
+		cs := piio.WithContext(source)
+
+		Convey("requests should return once the context is done.", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			_, err := cs.GetChunkContext(ctx, 0, 4)
+			So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+			close(source.release)
+		})
+		Convey("requests should work as before otherwise.", func() {
+			close(source.release)
+			c, err := cs.GetChunkContext(context.Background(), 2, 4)
+			So(err, ShouldBeNil)
+			So(piio.AsUncompressedChunk(c).Digits, ShouldResemble, piio.UncompressedPi[2:6])
+		})
+	})
+	Convey("Given a cached file", t, func() {
+		filename, err := piio.WriteHeaderedFile(piio.FileFormatCompressed, piio.UncompressedPi)
+		So(err, ShouldBeNil)
+		defer os.Remove(filename)
+
+		uncached := piio.NewUncachedChunkSource(filename, piio.FileFormatCompressed, 8)
+		cs := piio.NewCachedChunkSource(uncached, 8, 4, 1024)
+
+		Convey("both sources should support contexts natively.", func() {
+			_, ok := uncached.(piio.ChunkSourceContext)
+			So(ok, ShouldBeTrue)
+			So(piio.WithContext(cs), ShouldEqual, cs)
+		})
+		Convey("cancelled requests should fail without caching anything.", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, err := cs.GetChunkContext(ctx, 0, 8)
+			So(errors.Is(err, context.Canceled), ShouldBeTrue)
+			So(cs.Stats().Blocks, ShouldEqual, 0)
+
+			c, err := cs.GetChunkContext(context.Background(), 0, 8)
+			So(err, ShouldBeNil)
+			So(piio.AsUncompressedChunk(c).Digits, ShouldResemble, piio.UncompressedPi[:8])
+		})
+	})
+}
@@ -0,0 +1,226 @@
+package piio
+
+import (
+	"errors"
+	"io"
+)
+
+const (
+	// DenseBlockDigits is the amount of digits contained in
+	// one block of the dense file format. Blocks are the
+	// smallest byte aligned unit of that format.
+	DenseBlockDigits = 12
+	// DenseBlockBytes is the size of one block of the dense
+	// file format in bytes.
+	DenseBlockBytes = 5
+
+	denseGroupDigits = 3
+	denseGroupBits   = 10
+)
+
+// DenseChunk represents a densely packed chunk of digits
+// of pi. Each group of three digits is stored as a 10 bit
+// number between 0 and 999.
+type DenseChunk struct {
+	firstIndex int64
+	length     int
+	data       []byte
+	// skip is the amount of digits stored in front of the
+	// first digit of the chunk.
+	skip int
+}
+
+// ReadDenseChunk reads a certain chunk defined by the index
+// of the first requested digit of pi and the amount of digits
+// requested.
+// Both the first index and the size have to be positive.
+// The given file has to be seekable. If the file starts with a Header
+// it is validated and the chunk is limited to the amount of
+// digits it states.
+//
+// The expected file format is as follows.
+// Groups of three digits d0, d1, d2 are stored as the 10 bit
+// number d0*100 + d1*10 + d2. The groups are written as one
+// big endian bit stream, so every DenseBlockBytes bytes hold
+// exactly DenseBlockDigits digits. If the amount of digits is
+// not divisible by three the last group is padded with zeros.
+func ReadDenseChunk(input io.ReadSeeker, firstIndex int64, size int) (Chunk, error) {
+	err := checkChunkArguments(firstIndex, size)
+	if err != nil {
+		return nil, err
+	}
+	input, h, err := dataSection(input, FileFormatDense)
+	if err != nil {
+		return nil, err
+	}
+	size, err = limitSize(h, firstIndex, size)
+	if err != nil {
+		return nil, err
+	}
+	_, err = input.Seek(firstIndex/DenseBlockDigits*DenseBlockBytes, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, denseDataSize(int(firstIndex%DenseBlockDigits)+size))
+	n, err := input.Read(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Trim in case we requested more than the file can give us.
+	chnk := denseChunkAt(data[:n], firstIndex, size)
+	if chnk.length == 0 {
+		return nil, io.EOF
+	}
+	err = validateChunk(chnk)
+	if err != nil {
+		return nil, err
+	}
+	return chnk, nil
+}
+
+// denseChunkAt creates a DenseChunk of up to size digits
+// starting at firstIndex. The data has to start with the
+// block containing the first digit. The chunk is empty if
+// the data ends in front of the first digit.
+func denseChunkAt(data []byte, firstIndex int64, size int) *DenseChunk {
+	skip := int(firstIndex % DenseBlockDigits)
+	if available := len(data)*8/denseGroupBits*denseGroupDigits - skip; available < size {
+		size = available
+	}
+	if size < 0 {
+		size = 0
+	}
+	end := denseDataSize(skip + size)
+	return &DenseChunk{
+		firstIndex: firstIndex,
+		length:     size,
+		data:       data[:end:end],
+		skip:       skip,
+	}
+}
+
+// denseDataSize returns the amount of bytes needed to store
+// size densely packed digits.
+func denseDataSize(size int) int {
+	groups := (size + denseGroupDigits - 1) / denseGroupDigits
+	return (groups*denseGroupBits + 7) / 8
+}
+
+// CompressDense packs any chunk into a DenseChunk.
+func CompressDense(chnk Chunk) Chunk {
+	if _, ok := chnk.(*DenseChunk); ok {
+		return chnk
+	}
+
+	c := AsUncompressedChunk(chnk)
+
+	chunk := &DenseChunk{
+		firstIndex: c.FirstIndex(),
+		length:     len(c.Digits),
+		data:       make([]byte, denseDataSize(len(c.Digits))),
+	}
+	for g := 0; g*denseGroupDigits < len(c.Digits); g++ {
+		var group uint16
+		for i := g * denseGroupDigits; i < (g+1)*denseGroupDigits; i++ {
+			group *= 10
+			if i < len(c.Digits) {
+				group += uint16(c.Digits[i])
+			}
+		}
+		chunk.setGroup(g, group)
+	}
+	return chunk
+}
+
+func (c *DenseChunk) setGroup(g int, group uint16) {
+	bit := g * denseGroupBits
+	shifted := group << uint(16-denseGroupBits-bit%8)
+	c.data[bit/8] |= byte(shifted >> 8)
+	c.data[bit/8+1] |= byte(shifted)
+}
+
+// group returns the g-th group of digits and whether
+// it is valid.
+func (c *DenseChunk) group(g int) (uint16, bool) {
+	bit := g * denseGroupBits
+	group := uint16(c.data[bit/8])<<8 | uint16(c.data[bit/8+1])
+	group = (group >> uint(16-denseGroupBits-bit%8)) & 0x3FF
+	return group, group <= 999
+}
+
+func (c *DenseChunk) decompress() *UncompressedChunk {
+	chunk := &UncompressedChunk{
+		FirstDigitIndex: c.firstIndex,
+		Digits:          make([]byte, c.length),
+	}
+	for i := range chunk.Digits {
+		// Invalid groups are detected by validateChunk.
+		chunk.Digits[i], _ = c.Digit(c.firstIndex + int64(i))
+	}
+	return chunk
+}
+
+// IsCompressed returns true.
+func (c *DenseChunk) IsCompressed() bool {
+	return true
+}
+
+// FirstIndex returns the index of the first digit of
+// pi contained in this chunk.
+func (c *DenseChunk) FirstIndex() int64 {
+	return c.firstIndex
+}
+
+// Length returns the amount of digits contained in
+// this chunk.
+func (c *DenseChunk) Length() int {
+	return c.length
+}
+
+// LastIndex returns the index of the last digit of pi
+// contained in this chunk.
+func (c *DenseChunk) LastIndex() int64 {
+	return c.firstIndex + int64(c.Length()) - 1
+}
+
+// Digit returns the index-th digit of pi. It errors if
+// the requested digit is not contained in this chunk.
+// Only the group containing the digit is decoded.
+func (c *DenseChunk) Digit(index int64) (byte, error) {
+	if index < c.firstIndex || index > c.LastIndex() {
+		return 255, chunkOutOfRange(c, index)
+	}
+	pos := int(index-c.firstIndex) + c.skip
+
+	group, ok := c.group(pos / denseGroupDigits)
+	if !ok {
+		return 255, &CorruptDataError{
+			Index:  index,
+			Reason: "invalid group of digits",
+		}
+	}
+
+	switch pos % denseGroupDigits {
+	case 0:
+		return byte(group / 100), nil
+	case 1:
+		return byte(group / 10 % 10), nil
+	}
+	return byte(group % 10), nil
+}
+
+func writeDenseChunk(chnk *DenseChunk, w io.Writer) error {
+	if chnk.skip != 0 {
+		chnk = CompressDense(chnk.decompress()).(*DenseChunk)
+	}
+	n, err := w.Write(chnk.data)
+	if err != nil {
+		return err
+	}
+	if n != len(chnk.data) {
+		return errors.New("not all bytes could be written")
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package piio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var densePi = []byte{
+	0x4E, 0x89, 0xF4, 0x25, 0x67,
+}
+
+func TestDenseDigits(t *testing.T) {
+	Convey("Given a DenseChunk", t, func() {
+		chnk := &DenseChunk{
+			firstIndex: 0,
+			length:     12,
+			data:       densePi,
+		}
+
+		Convey("Digits() should", func() {
+			Convey("error on out of range inputs.", func() {
+				var err error
+				_, err = chnk.Digit(-1)
+				So(err, ShouldNotBeNil)
+				_, err = chnk.Digit(12)
+				So(err, ShouldNotBeNil)
+			})
+			Convey("work on all in range inputs.", func() {
+				for i, d := range uncompressedPi {
+					b, err := chnk.Digit(int64(i))
+					So(err, ShouldBeNil)
+					So(b, ShouldEqual, d)
+				}
+			})
+		})
+	})
+	Convey("Given a DenseChunk with an invalid group", t, func() {
+		chnk := &DenseChunk{
+			firstIndex: 0,
+			length:     3,
+			data:       []byte{0xFF, 0xC0},
+		}
+		Convey("Digits() should error.", func() {
+			_, err := chnk.Digit(1)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("reading it should error.", func() {
+			_, err := ReadDenseChunk(bytes.NewReader(chnk.data), 0, 3)
+			So(errors.Is(err, ErrCorruptData), ShouldBeTrue)
+		})
+	})
+}
+
+func TestDenseBasicGetters(t *testing.T) {
+	Convey("Given a basic DenseChunk", t, func() {
+		chnk := &DenseChunk{
+			firstIndex: 42,
+			length:     23,
+			data:       make([]byte, 10),
+		}
+		Convey("the is compressed getter should work.", func() {
+			So(chnk.IsCompressed(), ShouldBeTrue)
+		})
+		Convey("the first index getter should work.", func() {
+			So(chnk.FirstIndex(), ShouldEqual, 42)
+		})
+		Convey("the length getter should work.", func() {
+			So(chnk.Length(), ShouldEqual, 23)
+		})
+		Convey("the last index getter should work.", func() {
+			So(chnk.LastIndex(), ShouldEqual, 42+23-1)
+		})
+	})
+}
+
+func TestCompressDense(t *testing.T) {
+	Convey("Given an UncompressedChunk", t, func() {
+		chnk := &UncompressedChunk{
+			FirstDigitIndex: 0,
+			Digits:          uncompressedPi,
+		}
+		Convey("packing should work.", func() {
+			c := CompressDense(chnk)
+			dc, ok := c.(*DenseChunk)
+			So(ok, ShouldBeTrue)
+			So(dc.firstIndex, ShouldEqual, 0)
+			So(dc.length, ShouldEqual, 12)
+			So(dc.data, ShouldResemble, densePi)
+		})
+		Convey("packing an incomplete group should pad it.", func() {
+			c := CompressDense(&UncompressedChunk{Digits: uncompressedPi[:4]}).(*DenseChunk)
+			So(c.Length(), ShouldEqual, 4)
+			So(c.data, ShouldResemble, []byte{0x4E, 0x86, 0x40})
+			So(Decompress(c).(*UncompressedChunk).Digits, ShouldResemble, uncompressedPi[:4])
+		})
+	})
+	Convey("Given a CompressedChunk", t, func() {
+		chnk := &CompressedChunk{
+			firstIndex: 0,
+			data:       compressedPi,
+		}
+		Convey("packing should convert it.", func() {
+			c := CompressDense(chnk).(*DenseChunk)
+			So(c.data, ShouldResemble, densePi)
+		})
+	})
+	Convey("Given a DenseChunk", t, func() {
+		chnk := &DenseChunk{
+			firstIndex: 0,
+			length:     12,
+			data:       densePi,
+		}
+		Convey("packing shouldn't do anything.", func() {
+			So(CompressDense(chnk), ShouldEqual, chnk)
+		})
+		Convey("decompressing should work.", func() {
+			c := Decompress(chnk).(*UncompressedChunk)
+			So(c.Digits, ShouldResemble, uncompressedPi)
+		})
+		Convey("compressing should convert it.", func() {
+			c := Compress(chnk).(*CompressedChunk)
+			So(c.data, ShouldResemble, compressedPi)
+		})
+	})
+}
+
+func TestReadDenseChunk(t *testing.T) {
+	Convey("Given a dense file", t, func() {
+		file := bytes.NewReader(append(append([]byte{}, densePi...), densePi...))
+
+		Convey("reading an unaligned chunk should work.", func() {
+			c, err := ReadDenseChunk(file, 7, 10)
+			So(err, ShouldBeNil)
+			So(c.FirstIndex(), ShouldEqual, 7)
+			So(c.Length(), ShouldEqual, 10)
+			So(Decompress(c).(*UncompressedChunk).Digits, ShouldResemble, append(append([]byte{}, uncompressedPi[7:]...), uncompressedPi[:5]...))
+
+			buf := &bytes.Buffer{}
+			So(WriteChunk(c, FileFormatDense, buf), ShouldBeNil)
+			So(buf.Bytes(), ShouldResemble, CompressDense(Decompress(c)).(*DenseChunk).data)
+		})
+		Convey("reading an aligned chunk should work.", func() {
+			c, err := ReadDenseChunk(file, 12, 5)
+			So(err, ShouldBeNil)
+			So(c.FirstIndex(), ShouldEqual, 12)
+			So(c.Length(), ShouldEqual, 5)
+			So(Decompress(c).(*UncompressedChunk).Digits, ShouldResemble, uncompressedPi[:5])
+		})
+		Convey("reading beyond the end should trim the chunk.", func() {
+			c, err := ReadDenseChunk(file, 12, 24)
+			So(err, ShouldBeNil)
+			So(c.Length(), ShouldEqual, 12)
+		})
+	})
+	Convey("Given a short dense file", t, func() {
+		// 13 digits fit into 7 bytes.
+		file := bytes.NewReader(CompressDense(&UncompressedChunk{Digits: append(append([]byte{}, uncompressedPi...), 9)}).(*DenseChunk).data)
+
+		Convey("reading the last group should work.", func() {
+			// Without a header the padding cannot be told apart.
+			c, err := ReadDenseChunk(file, 12, 4)
+			So(err, ShouldBeNil)
+			So(c.Length(), ShouldEqual, 3)
+			So(Decompress(c).(*UncompressedChunk).Digits, ShouldResemble, []byte{9, 0, 0})
+		})
+		Convey("reading beyond the end of the last block should return io.EOF.", func() {
+			c, err := ReadDenseChunk(file, 20, 2)
+			So(err, ShouldEqual, io.EOF)
+			So(c, ShouldBeNil)
+		})
+	})
+}
+
+func TestWriteDenseChunk(t *testing.T) {
+	Convey("Given an UncompressedChunk", t, func() {
+		chnk := &UncompressedChunk{
+			FirstDigitIndex: 0,
+			Digits:          uncompressedPi,
+		}
+		Convey("writing it densely should work.", func() {
+			buf := &bytes.Buffer{}
+			err := WriteChunk(chnk, FileFormatDense, buf)
+			So(err, ShouldBeNil)
+			So(buf.Bytes(), ShouldResemble, densePi)
+		})
+	})
+}
@@ -0,0 +1,153 @@
+package piio
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultDiffBlockSize is the amount of digits compared at
+// once by Diff unless configured otherwise. It is a multiple
+// of DenseBlockDigits, so reads are aligned in all formats.
+const DefaultDiffBlockSize = 1 << 16 * DenseBlockDigits
+
+// DiffResult is the result of comparing two ChunkSources.
+type DiffResult struct {
+	// FirstMismatch is the index of the first digit that
+	// differs or -1 if all common digits are equal.
+	FirstMismatch int64
+	// Mismatches is the amount of differing digits within
+	// the digits available from both sources.
+	Mismatches int64
+	// LengthA and LengthB are the amounts of digits
+	// available from the sources.
+	LengthA int64
+	LengthB int64
+}
+
+// Equal returns true if both sources contain the same digits.
+func (r *DiffResult) Equal() bool {
+	return r.Mismatches == 0 && r.LengthA == r.LengthB
+}
+
+// LengthDifference returns the amount of digits source A
+// has more than source B.
+func (r *DiffResult) LengthDifference() int64 {
+	return r.LengthA - r.LengthB
+}
+
+// Diff compares the digits available from both sources.
+// The digits are read in blocks of blockSize digits, which
+// have to be supported by both sources, by the given amount
+// of goroutines.
+func Diff(ctx context.Context, a, b ChunkSource, blockSize, workers int) (*DiffResult, error) {
+	err := checkChunkRequest(0, blockSize, a.MaximumChunkSize())
+	if err == nil {
+		err = checkChunkRequest(0, blockSize, b.MaximumChunkSize())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		return nil, &InvalidArgumentError{"at least one worker is needed"}
+	}
+
+	result := &DiffResult{
+		FirstMismatch: -1,
+	}
+	result.LengthA, err = a.AvailableDigits()
+	if err != nil {
+		return nil, err
+	}
+	result.LengthB, err = b.AvailableDigits()
+	if err != nil {
+		return nil, err
+	}
+	common := result.LengthA
+	if result.LengthB < common {
+		common = result.LengthB
+	}
+	blocks := (common + int64(blockSize) - 1) / int64(blockSize)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mutex sync.Mutex
+	var firstErr error
+	// Blocks are handed out in order, so the files are
+	// read sequentially.
+	next := int64(-1)
+
+	wg := &sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				block := atomic.AddInt64(&next, 1)
+				if block >= blocks || ctx.Err() != nil {
+					return
+				}
+				first := block * int64(blockSize)
+				size := blockSize
+				if common-first < int64(size) {
+					size = int(common - first)
+				}
+
+				mismatches, firstMismatch, err := diffBlock(ctx, a, b, first, size)
+
+				mutex.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				result.Mismatches += mismatches
+				if firstMismatch >= 0 && (result.FirstMismatch < 0 || firstMismatch < result.FirstMismatch) {
+					result.FirstMismatch = firstMismatch
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return result, ctx.Err()
+}
+
+// diffBlock compares the given digits of both sources.
+// It returns the amount of differing digits and the index
+// of the first one.
+func diffBlock(ctx context.Context, a, b ChunkSource, firstIndex int64, size int) (int64, int64, error) {
+	ca, err := GetChunkContext(ctx, a, firstIndex, size)
+	if err != nil {
+		return 0, -1, err
+	}
+	cb, err := GetChunkContext(ctx, b, firstIndex, size)
+	if err != nil {
+		return 0, -1, err
+	}
+	da := AsUncompressedChunk(ca).Digits
+	db := AsUncompressedChunk(cb).Digits
+	if len(da) != size || len(db) != size {
+		return 0, -1, &CorruptDataError{firstIndex, "fewer digits than available"}
+	}
+	if bytes.Equal(da, db) {
+		return 0, -1, nil
+	}
+
+	var mismatches int64
+	firstMismatch := int64(-1)
+	for i := range da {
+		if da[i] != db[i] {
+			if firstMismatch < 0 {
+				firstMismatch = firstIndex + int64(i)
+			}
+			mismatches++
+		}
+	}
+	return mismatches, firstMismatch, nil
+}
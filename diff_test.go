@@ -0,0 +1,62 @@
+package piio_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/targodan/piio"
+	"github.com/targodan/piio/internal/piiotest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// decimalValues returns the values of the decimal digits.
+func decimalValues(s string) []byte {
+	values := make([]byte, len(s))
+	for i := range s {
+		values[i] = s[i] - '0'
+	}
+	return values
+}
+
+func TestDiff(t *testing.T) {
+	pi := decimalValues(piio.PiDecimals)
+
+	Convey("Given two equal sources", t, func() {
+		a := &piiotest.MemoryChunkSource{Digits: pi, MaxSize: 8}
+		b := &piiotest.MemoryChunkSource{Digits: decimalValues(piio.PiDecimals), MaxSize: 8}
+
+		Convey("no differences should be found.", func() {
+			result, err := piio.Diff(context.Background(), a, b, 8, 3)
+			So(err, ShouldBeNil)
+			So(result.Equal(), ShouldBeTrue)
+			So(result.FirstMismatch, ShouldEqual, -1)
+			So(result.Mismatches, ShouldEqual, 0)
+			So(result.LengthDifference(), ShouldEqual, 0)
+		})
+		Convey("a block size larger than supported should fail.", func() {
+			_, err := piio.Diff(context.Background(), a, b, 16, 3)
+			So(errors.Is(err, piio.ErrChunkTooLarge), ShouldBeTrue)
+		})
+	})
+	Convey("Given two different sources", t, func() {
+		digits := decimalValues(piio.PiDecimals)
+		digits[13] = (digits[13] + 1) % 10
+		digits[30] = (digits[30] + 1) % 10
+		digits[31] = (digits[31] + 1) % 10
+		a := &piiotest.MemoryChunkSource{Digits: pi, MaxSize: 8}
+		b := &piiotest.MemoryChunkSource{Digits: digits[:40], MaxSize: 8}
+
+		Convey("the differences should be reported.", func() {
+			result, err := piio.Diff(context.Background(), a, b, 8, 3)
+			So(err, ShouldBeNil)
+			So(result.Equal(), ShouldBeFalse)
+			So(result.FirstMismatch, ShouldEqual, 13)
+			So(result.Mismatches, ShouldEqual, 3)
+			So(result.LengthA, ShouldEqual, len(pi))
+			So(result.LengthB, ShouldEqual, 40)
+			So(result.LengthDifference(), ShouldEqual, len(pi)-40)
+		})
+	})
+}
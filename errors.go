@@ -0,0 +1,173 @@
+package piio
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrOutOfRange is matched by errors for digits that
+	// are not available. See OutOfRangeError.
+	ErrOutOfRange = errors.New("index out of range")
+	// ErrChunkTooLarge is matched by errors for chunks
+	// exceeding the maximum chunk size. See ChunkTooLargeError.
+	ErrChunkTooLarge = errors.New("chunk too large")
+	// ErrInvalidArgument is matched by errors for invalid
+	// requests like negative sizes. See InvalidArgumentError.
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrUnsupportedFormat is matched by errors for files
+	// or file formats that cannot be handled. See
+	// UnsupportedFormatError.
+	ErrUnsupportedFormat = errors.New("unsupported file format")
+	// ErrCorruptData is matched by errors for invalid stored
+	// data. See CorruptDataError.
+	ErrCorruptData = errors.New("corrupt data")
+)
+
+// Range describes a range of digits of pi.
+type Range struct {
+	FirstIndex int64 `json:"firstIndex"`
+	Size       int64 `json:"size"`
+}
+
+// LastIndex returns the index of the last digit in the range.
+func (r Range) LastIndex() int64 {
+	return r.FirstIndex + r.Size - 1
+}
+
+// Contains returns whether the digit with the given index
+// is part of the range.
+func (r Range) Contains(index int64) bool {
+	return r.FirstIndex <= index && index <= r.LastIndex()
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("[%d, %d)", r.FirstIndex, r.FirstIndex+r.Size)
+}
+
+// OutOfRangeError is returned if the requested digits are
+// not available. It matches ErrOutOfRange.
+type OutOfRangeError struct {
+	Requested Range
+	Available Range
+}
+
+func (e *OutOfRangeError) Error() string {
+	return fmt.Sprintf("index out of range, requested digits %s but only digits %s are available", e.Requested, e.Available)
+}
+
+// Is makes the error match ErrOutOfRange.
+func (e *OutOfRangeError) Is(target error) bool {
+	return target == ErrOutOfRange
+}
+
+// ChunkTooLargeError is returned if the requested chunk
+// exceeds the maximum chunk size of a ChunkSource.
+type ChunkTooLargeError struct {
+	Size        int
+	MaximumSize int
+}
+
+func (e *ChunkTooLargeError) Error() string {
+	return fmt.Sprintf("requested chunk of size %d but only supporting chunks of size up to %d", e.Size, e.MaximumSize)
+}
+
+// Is makes the error match ErrChunkTooLarge.
+func (e *ChunkTooLargeError) Is(target error) bool {
+	return target == ErrChunkTooLarge
+}
+
+// InvalidArgumentError is returned for invalid requests.
+type InvalidArgumentError struct {
+	Reason string
+}
+
+func (e *InvalidArgumentError) Error() string {
+	return e.Reason
+}
+
+// Is makes the error match ErrInvalidArgument.
+func (e *InvalidArgumentError) Is(target error) bool {
+	return target == ErrInvalidArgument
+}
+
+// UnsupportedFormatError is returned for files or file
+// formats that cannot be handled.
+type UnsupportedFormatError struct {
+	Reason string
+}
+
+func (e *UnsupportedFormatError) Error() string {
+	return e.Reason
+}
+
+// Is makes the error match ErrUnsupportedFormat.
+func (e *UnsupportedFormatError) Is(target error) bool {
+	return target == ErrUnsupportedFormat
+}
+
+// CorruptDataError is returned if stored data is invalid.
+// Index is the index of the first affected digit or -1 if
+// the error does not relate to a specific digit.
+type CorruptDataError struct {
+	Index  int64
+	Reason string
+}
+
+func (e *CorruptDataError) Error() string {
+	if e.Index < 0 {
+		return e.Reason
+	}
+	return fmt.Sprintf("%s at index %d", e.Reason, e.Index)
+}
+
+// Is makes the error match ErrCorruptData.
+func (e *CorruptDataError) Is(target error) bool {
+	return target == ErrCorruptData
+}
+
+// checkChunkRequest validates a request for a chunk
+// against the maximum chunk size of a ChunkSource.
+func checkChunkRequest(firstIndex int64, size, maxSize int) error {
+	if size > maxSize {
+		return &ChunkTooLargeError{
+			Size:        size,
+			MaximumSize: maxSize,
+		}
+	}
+	return checkChunkArguments(firstIndex, size)
+}
+
+func checkChunkArguments(firstIndex int64, size int) error {
+	if firstIndex < 0 {
+		return &InvalidArgumentError{"only positive first indexes are supported"}
+	}
+	if size <= 0 {
+		return &InvalidArgumentError{"only positive sizes are supported"}
+	}
+	return nil
+}
+
+// outOfRange creates an OutOfRangeError for a request to
+// the given source.
+func outOfRange(cs ChunkSource, firstIndex int64, size int) error {
+	avail, err := cs.AvailableDigits()
+	if err != nil {
+		return err
+	}
+	return &OutOfRangeError{
+		Requested: Range{FirstIndex: firstIndex, Size: int64(size)},
+		Available: Range{FirstIndex: 0, Size: avail},
+	}
+}
+
+// chunkOutOfRange creates an OutOfRangeError for a digit
+// that is not contained in the given chunk.
+func chunkOutOfRange(chnk Chunk, index int64) error {
+	return &OutOfRangeError{
+		Requested: Range{FirstIndex: index, Size: 1},
+		Available: Range{FirstIndex: chnk.FirstIndex(), Size: int64(chnk.Length())},
+	}
+}
+
+var errUnknownFileFormat = &UnsupportedFormatError{"unknown file format"}
@@ -0,0 +1,93 @@
+package piio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestErrors(t *testing.T) {
+	Convey("Given a compressed file with a header", t, func() {
+		filename, err := writeHeaderedFile(FileFormatCompressed, uncompressedPi)
+		So(err, ShouldBeNil)
+		defer os.Remove(filename)
+
+		cs := NewUncachedChunkSource(filename, FileFormatCompressed, 8)
+
+		Convey("requests beyond the end should return an OutOfRangeError.", func() {
+			_, err := cs.GetChunk(int64(len(uncompressedPi)), 2)
+			So(errors.Is(err, ErrOutOfRange), ShouldBeTrue)
+			So(errors.Is(err, io.EOF), ShouldBeFalse)
+
+			var rangeErr *OutOfRangeError
+			So(errors.As(err, &rangeErr), ShouldBeTrue)
+			So(rangeErr.Requested, ShouldResemble, Range{FirstIndex: int64(len(uncompressedPi)), Size: 2})
+			So(rangeErr.Available, ShouldResemble, Range{FirstIndex: 0, Size: int64(len(uncompressedPi))})
+		})
+		Convey("too large requests should return a ChunkTooLargeError.", func() {
+			_, err := cs.GetChunk(0, 9)
+			So(errors.Is(err, ErrChunkTooLarge), ShouldBeTrue)
+
+			var sizeErr *ChunkTooLargeError
+			So(errors.As(err, &sizeErr), ShouldBeTrue)
+			So(sizeErr.MaximumSize, ShouldEqual, 8)
+		})
+		Convey("negative requests should return an InvalidArgumentError.", func() {
+			_, err := cs.GetChunk(-1, 2)
+			So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+			_, err = cs.GetChunk(0, 0)
+			So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+		})
+		Convey("reading it as another format should return ErrUnsupportedFormat.", func() {
+			err := ValidateFile(filename, FileFormatDense)
+			So(errors.Is(err, ErrUnsupportedFormat), ShouldBeTrue)
+		})
+		Convey("a modified file should return ErrCorruptData.", func() {
+			file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0)
+			So(err, ShouldBeNil)
+			_, err = file.Write([]byte{0x11})
+			So(err, ShouldBeNil)
+			So(file.Close(), ShouldBeNil)
+
+			So(errors.Is(VerifyFile(filename, FileFormatCompressed), ErrCorruptData), ShouldBeTrue)
+		})
+	})
+	Convey("Given a chunk", t, func() {
+		chnk, err := ReadCompressedChunk(bytes.NewReader(compressedPi), 2, 4)
+		So(err, ShouldBeNil)
+
+		Convey("digits outside of it should return an OutOfRangeError.", func() {
+			_, err := chnk.Digit(6)
+			var rangeErr *OutOfRangeError
+			So(errors.As(err, &rangeErr), ShouldBeTrue)
+			So(rangeErr.Available, ShouldResemble, Range{FirstIndex: 2, Size: 4})
+		})
+	})
+	Convey("Given a DenseChunk with an invalid group", t, func() {
+		chnk := &DenseChunk{
+			firstIndex: 6,
+			length:     3,
+			data:       []byte{0xFF, 0xC0},
+		}
+		Convey("Digit() should return a CorruptDataError.", func() {
+			_, err := chnk.Digit(7)
+			var dataErr *CorruptDataError
+			So(errors.As(err, &dataErr), ShouldBeTrue)
+			So(dataErr.Index, ShouldEqual, 7)
+		})
+	})
+	Convey("A Range should", t, func() {
+		r := Range{FirstIndex: 10, Size: 5}
+		Convey("know its last index.", func() {
+			So(r.LastIndex(), ShouldEqual, 14)
+			So(r.Contains(10), ShouldBeTrue)
+			So(r.Contains(14), ShouldBeTrue)
+			So(r.Contains(15), ShouldBeFalse)
+			So(r.Contains(9), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,11 @@
+package piio
+
+// The test data and helpers used by the tests of package
+// piio_test, which share the ChunkSource of piiotest.
+var (
+	UncompressedPi    = uncompressedPi
+	TextPi            = textPi
+	PiDecimals        = piDecimals
+	DigitString       = digitString
+	WriteHeaderedFile = writeHeaderedFile
+)
@@ -1,9 +1,14 @@
 module github.com/targodan/piio
 
-go 1.12
+go 1.20
 
 require (
 	github.com/julienschmidt/httprouter v1.2.0
-	github.com/targodan/go-errors v0.0.0-20180112090806-8f9e51621795
+	github.com/smartystreets/goconvey v1.6.4
 	gopkg.in/urfave/cli.v1 v1.20.0
 )
+
+require (
+	github.com/jtolds/gls v4.20.0+incompatible // indirect
+	github.com/smartystreets/assertions v0.0.0-20180927180507-b2de0cb4f26d // indirect
+)
@@ -0,0 +1,358 @@
+package piio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// HeaderVersion is the version of the header written
+// by this package.
+const HeaderVersion = 1
+
+var headerMagic = []byte("PIIO")
+
+// headerFixedSize is the size of a header without the
+// name of the constant.
+const headerFixedSize = 4 + 2 + 1 + 1 + 8 + 8 + 4 + 1
+
+// ErrNoHeader is returned when reading the header of a
+// file that does not start with one.
+var ErrNoHeader = errors.New("file has no header")
+
+// Header describes the contents of a digit file.
+//
+// The binary layout is as follows, all numbers are big endian.
+//
+//	4 bytes  magic "PIIO"
+//	uint16   version
+//	uint8    base of the digits
+//	uint8    file format of the data
+//	uint64   exact amount of digits
+//	uint64   offset of the first digit in bytes
+//	uint32   CRC-32 (IEEE) checksum of the data
+//	uint8    length of the name of the constant
+//	n bytes  name of the constant
+type Header struct {
+	Version    uint16
+	Constant   string
+	Base       uint8
+	Format     FileFormat
+	DigitCount int64
+	DataOffset int64
+	Checksum   uint32
+}
+
+// ReadHeader reads a header from the input. It returns
+// ErrNoHeader if the input does not start with the magic
+// bytes of a header.
+func ReadHeader(input io.Reader) (*Header, error) {
+	fixed := make([]byte, headerFixedSize)
+	n, err := io.ReadFull(input, fixed)
+	if n < len(headerMagic) || !bytes.Equal(fixed[:len(headerMagic)], headerMagic) {
+		return nil, ErrNoHeader
+	}
+	if err != nil {
+		return nil, &CorruptDataError{-1, "truncated header"}
+	}
+
+	h := &Header{
+		Version:    binary.BigEndian.Uint16(fixed[4:]),
+		Base:       fixed[6],
+		Format:     FileFormat(fixed[7]),
+		DigitCount: int64(binary.BigEndian.Uint64(fixed[8:])),
+		DataOffset: int64(binary.BigEndian.Uint64(fixed[16:])),
+		Checksum:   binary.BigEndian.Uint32(fixed[24:]),
+	}
+
+	name := make([]byte, fixed[28])
+	_, err = io.ReadFull(input, name)
+	if err != nil {
+		return nil, &CorruptDataError{-1, "truncated header"}
+	}
+	h.Constant = string(name)
+
+	if h.Version == 0 || h.Version > HeaderVersion {
+		return nil, &UnsupportedFormatError{fmt.Sprintf("unsupported header version %d", h.Version)}
+	}
+	if h.DigitCount < 0 || h.DataOffset < int64(headerFixedSize+len(name)) {
+		return nil, &CorruptDataError{-1, "invalid header"}
+	}
+	return h, nil
+}
+
+// WriteHeader writes the header to w. The version and
+// data offset are set by WriteHeader, the data is expected
+// to follow the header directly.
+func WriteHeader(h *Header, w io.Writer) error {
+	if len(h.Constant) > 255 {
+		return &InvalidArgumentError{"the name of the constant is too long"}
+	}
+	h.Version = HeaderVersion
+	h.DataOffset = int64(headerFixedSize + len(h.Constant))
+
+	data := make([]byte, h.DataOffset)
+	copy(data, headerMagic)
+	binary.BigEndian.PutUint16(data[4:], h.Version)
+	data[6] = h.Base
+	data[7] = byte(h.Format)
+	binary.BigEndian.PutUint64(data[8:], uint64(h.DigitCount))
+	binary.BigEndian.PutUint64(data[16:], uint64(h.DataOffset))
+	binary.BigEndian.PutUint32(data[24:], h.Checksum)
+	data[28] = byte(len(h.Constant))
+	copy(data[headerFixedSize:], h.Constant)
+
+	n, err := w.Write(data)
+	if err != nil {
+		return err
+	}
+	if n != len(data) {
+		return errors.New("not all bytes could be written")
+	}
+	return nil
+}
+
+// Validate checks whether the header describes decimal
+// digits stored in the given file format.
+func (h *Header) Validate(format FileFormat) error {
+	if h.Base != 10 {
+		return &UnsupportedFormatError{fmt.Sprintf("only base 10 is supported, file has base %d", h.Base)}
+	}
+	if h.Format != format {
+		return &UnsupportedFormatError{fmt.Sprintf("expected file format %s, file has format %s", format, h.Format)}
+	}
+	return nil
+}
+
+// DataSize returns the expected size of the data in bytes.
+func (h *Header) DataSize() (int64, error) {
+	switch h.Format {
+	case FileFormatCompressed:
+		return (h.DigitCount + 1) / 2, nil
+
+	case FileFormatText:
+		return h.DigitCount, nil
+
+	case FileFormatDense:
+		blocks := h.DigitCount / DenseBlockDigits
+		return blocks*DenseBlockBytes + int64(denseDataSize(int(h.DigitCount%DenseBlockDigits))), nil
+	}
+	return 0, errUnknownFileFormat
+}
+
+// readHeader reads the header at the start of the input.
+// It returns nil if the input has no header.
+func readHeader(input io.ReadSeeker) (*Header, error) {
+	_, err := input.Seek(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	h, err := ReadHeader(input)
+	if err == ErrNoHeader {
+		return nil, nil
+	}
+	return h, err
+}
+
+// offsetReadSeeker hides the header of a file by
+// shifting all absolute seeks by the data offset.
+type offsetReadSeeker struct {
+	io.ReadSeeker
+	offset int64
+}
+
+func (r *offsetReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	if whence == 0 {
+		offset += r.offset
+	}
+	pos, err := r.ReadSeeker.Seek(offset, whence)
+	return pos - r.offset, err
+}
+
+// dataSection validates the header of the input if it has
+// one and returns a ReadSeeker positioned relative to the
+// first digit. The returned header is nil for headerless
+// inputs.
+func dataSection(input io.ReadSeeker, format FileFormat) (io.ReadSeeker, *Header, error) {
+	h, err := readHeader(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	if h == nil {
+		return input, nil, nil
+	}
+	err = h.Validate(format)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &offsetReadSeeker{
+		ReadSeeker: input,
+		offset:     h.DataOffset,
+	}, h, nil
+}
+
+// ReadFileHeader reads the header of the given file. It
+// returns ErrNoHeader if the file has no header.
+func ReadFileHeader(filename string) (*Header, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadHeader(file)
+}
+
+// ValidateFile checks that the header of the given file
+// matches the given file format and that the size of the
+// file matches the amount of digits in the header.
+// Files without a header are accepted as they are.
+func ValidateFile(filename string, format FileFormat) error {
+	_, _, err := validateFile(filename, format)
+	return err
+}
+
+// VerifyFile does the same as ValidateFile and also checks
+// that the checksum of the data matches the header.
+func VerifyFile(filename string, format FileFormat) error {
+	h, file, err := validateFile(filename, format)
+	if err != nil || h == nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Seek(h.DataOffset, 0)
+	if err != nil {
+		return err
+	}
+	crc := crc32.NewIEEE()
+	_, err = io.Copy(crc, file)
+	if err != nil {
+		return err
+	}
+	if crc.Sum32() != h.Checksum {
+		return &CorruptDataError{-1, fmt.Sprintf("checksum mismatch, header says %08x but data has %08x", h.Checksum, crc.Sum32())}
+	}
+	return nil
+}
+
+// validateFile validates the file and returns its header
+// along with the opened file. The file is closed if there
+// is no header or an error occurs.
+func validateFile(filename string, format FileFormat) (*Header, *os.File, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h, err := ReadHeader(file)
+	if err == ErrNoHeader {
+		file.Close()
+		return nil, nil, nil
+	}
+	if err == nil {
+		err = h.Validate(format)
+	}
+	if err == nil {
+		err = validateDataSize(h, file)
+	}
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("invalid file \"%s\": %w", filename, err)
+	}
+	return h, file, nil
+}
+
+func validateDataSize(h *Header, file *os.File) error {
+	fi, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	size, err := h.DataSize()
+	if err != nil {
+		return err
+	}
+	if fi.Size()-h.DataOffset != size {
+		return &CorruptDataError{-1, fmt.Sprintf("expected %d bytes of data for %d digits, got %d", size, h.DigitCount, fi.Size()-h.DataOffset)}
+	}
+	return nil
+}
+
+// FileWriter writes a digit file starting with a Header.
+// The amount of digits and the checksum in the header are
+// filled in on Close, so the underlying writer has to be
+// seekable.
+type FileWriter struct {
+	w      io.WriteSeeker
+	header Header
+	crc    hash.Hash32
+}
+
+// NewFileWriter creates a new FileWriter writing digits of
+// pi in the given file format to w. It writes a preliminary
+// header right away.
+func NewFileWriter(w io.WriteSeeker, format FileFormat) (*FileWriter, error) {
+	fw := &FileWriter{
+		w: w,
+		header: Header{
+			Constant: "pi",
+			Base:     10,
+			Format:   format,
+		},
+		crc: crc32.NewIEEE(),
+	}
+	_, err := fw.header.DataSize()
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Seek(0, 1)
+	if err != nil {
+		return nil, &InvalidArgumentError{"writing a header requires a seekable output"}
+	}
+	err = WriteHeader(&fw.header, w)
+	if err != nil {
+		return nil, err
+	}
+	return fw, nil
+}
+
+// WriteChunk appends the given chunk to the file. Chunks
+// have to be written in order, see WriteChunk for further
+// restrictions.
+func (fw *FileWriter) WriteChunk(chnk Chunk) error {
+	err := WriteChunk(chnk, fw.header.Format, io.MultiWriter(fw.w, fw.crc))
+	if err != nil {
+		return err
+	}
+	fw.header.DigitCount += int64(chnk.Length())
+	return nil
+}
+
+// Header returns the header as it is going to be written
+// on Close.
+func (fw *FileWriter) Header() Header {
+	h := fw.header
+	h.Checksum = fw.crc.Sum32()
+	return h
+}
+
+// Close completes the header. It does not close the
+// underlying writer.
+func (fw *FileWriter) Close() error {
+	fw.header.Checksum = fw.crc.Sum32()
+	_, err := fw.w.Seek(0, 0)
+	if err != nil {
+		return err
+	}
+	err = WriteHeader(&fw.header, fw.w)
+	if err != nil {
+		return err
+	}
+	_, err = fw.w.Seek(0, 2)
+	return err
+}
@@ -0,0 +1,131 @@
+package piio
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func writeHeaderedFile(format FileFormat, digits []byte) (string, error) {
+	file, err := ioutil.TempFile("", "piio")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	fw, err := NewFileWriter(file, format)
+	if err != nil {
+		return "", err
+	}
+	err = fw.WriteChunk(&UncompressedChunk{Digits: digits})
+	if err != nil {
+		return "", err
+	}
+	return file.Name(), fw.Close()
+}
+
+func TestHeader(t *testing.T) {
+	Convey("Given a header", t, func() {
+		h := &Header{
+			Constant:   "pi",
+			Base:       10,
+			Format:     FileFormatDense,
+			DigitCount: 1000000001,
+			Checksum:   0xDEADBEEF,
+		}
+		Convey("writing and reading it should work.", func() {
+			buf := &bytes.Buffer{}
+			So(WriteHeader(h, buf), ShouldBeNil)
+			So(h.Version, ShouldEqual, HeaderVersion)
+			So(h.DataOffset, ShouldEqual, buf.Len())
+
+			read, err := ReadHeader(buf)
+			So(err, ShouldBeNil)
+			So(read, ShouldResemble, h)
+		})
+		Convey("validating it should check the format.", func() {
+			So(h.Validate(FileFormatDense), ShouldBeNil)
+			So(h.Validate(FileFormatCompressed), ShouldNotBeNil)
+		})
+	})
+	Convey("Given data without a header", t, func() {
+		Convey("reading a header should return ErrNoHeader.", func() {
+			_, err := ReadHeader(bytes.NewReader(compressedPi))
+			So(err, ShouldEqual, ErrNoHeader)
+		})
+	})
+	Convey("Given a truncated header", t, func() {
+		Convey("reading it should fail.", func() {
+			_, err := ReadHeader(bytes.NewReader([]byte("PIIO\x00\x01")))
+			So(err, ShouldNotBeNil)
+			So(err, ShouldNotEqual, ErrNoHeader)
+		})
+	})
+}
+
+func TestFileWriter(t *testing.T) {
+	Convey("Given a compressed file with a header and an odd amount of digits", t, func() {
+		filename, err := writeHeaderedFile(FileFormatCompressed, uncompressedPi[:11])
+		So(err, ShouldBeNil)
+		defer os.Remove(filename)
+
+		Convey("the header should be complete.", func() {
+			h, err := ReadFileHeader(filename)
+			So(err, ShouldBeNil)
+			So(h.Constant, ShouldEqual, "pi")
+			So(h.DigitCount, ShouldEqual, 11)
+			So(VerifyFile(filename, FileFormatCompressed), ShouldBeNil)
+			So(VerifyFile(filename, FileFormatText), ShouldNotBeNil)
+		})
+		Convey("the available digits should be exact.", func() {
+			avail, err := NewUncachedChunkSource(filename, FileFormatCompressed, 16).AvailableDigits()
+			So(err, ShouldBeNil)
+			So(avail, ShouldEqual, 11)
+		})
+		Convey("reading should skip the header.", func() {
+			file, err := os.Open(filename)
+			So(err, ShouldBeNil)
+			defer file.Close()
+
+			c, err := ReadCompressedChunk(file, 2, 4)
+			So(err, ShouldBeNil)
+			So(Decompress(c).(*UncompressedChunk).Digits, ShouldResemble, uncompressedPi[2:6])
+
+			_, err = ReadCompressedChunk(file, 12, 2)
+			So(err, ShouldEqual, io.EOF)
+
+			_, err = ReadTextChunk(file, 0, 2)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("a corrupted file should fail verification.", func() {
+			file, err := os.OpenFile(filename, os.O_RDWR, 0)
+			So(err, ShouldBeNil)
+			_, err = file.WriteAt([]byte{0x99}, 32)
+			So(err, ShouldBeNil)
+			So(file.Close(), ShouldBeNil)
+
+			So(ValidateFile(filename, FileFormatCompressed), ShouldBeNil)
+			So(VerifyFile(filename, FileFormatCompressed), ShouldNotBeNil)
+		})
+	})
+	Convey("Given a text file with a header", t, func() {
+		filename, err := writeHeaderedFile(FileFormatText, uncompressedPi)
+		So(err, ShouldBeNil)
+		defer os.Remove(filename)
+
+		Convey("the available digits should be exact.", func() {
+			avail, err := NewUncachedChunkSource(filename, FileFormatText, 16).AvailableDigits()
+			So(err, ShouldBeNil)
+			So(avail, ShouldEqual, 12)
+		})
+		Convey("reading should skip the header.", func() {
+			c, err := NewUncachedChunkSource(filename, FileFormatText, 16).GetChunk(10, 4)
+			So(err, ShouldBeNil)
+			So(c.(*UncompressedChunk).Digits, ShouldResemble, uncompressedPi[10:])
+		})
+	})
+}
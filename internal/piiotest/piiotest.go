@@ -0,0 +1,55 @@
+// Package piiotest provides helpers for the tests of the
+// packages using ChunkSources.
+package piiotest
+
+import (
+	"sync/atomic"
+
+	"github.com/targodan/piio"
+)
+
+// MemoryChunkSource serves digit values from memory and
+// counts the chunks requested from it.
+type MemoryChunkSource struct {
+	Digits  []byte
+	MaxSize int
+
+	calls int32
+}
+
+func (cs *MemoryChunkSource) GetChunk(firstIndex int64, size int) (piio.Chunk, error) {
+	atomic.AddInt32(&cs.calls, 1)
+	if firstIndex < 0 || size <= 0 {
+		return nil, &piio.InvalidArgumentError{Reason: "only positive first indexes and sizes are supported"}
+	}
+	if size > cs.MaxSize {
+		return nil, &piio.ChunkTooLargeError{Size: size, MaximumSize: cs.MaxSize}
+	}
+	if firstIndex >= int64(len(cs.Digits)) {
+		return nil, &piio.OutOfRangeError{
+			Requested: piio.Range{FirstIndex: firstIndex, Size: int64(size)},
+			Available: piio.Range{FirstIndex: 0, Size: int64(len(cs.Digits))},
+		}
+	}
+	last := firstIndex + int64(size)
+	if last > int64(len(cs.Digits)) {
+		last = int64(len(cs.Digits))
+	}
+	return &piio.UncompressedChunk{
+		FirstDigitIndex: firstIndex,
+		Digits:          cs.Digits[firstIndex:last],
+	}, nil
+}
+
+func (cs *MemoryChunkSource) AvailableDigits() (int64, error) {
+	return int64(len(cs.Digits)), nil
+}
+
+func (cs *MemoryChunkSource) MaximumChunkSize() int {
+	return cs.MaxSize
+}
+
+// Calls returns the amount of chunks requested so far.
+func (cs *MemoryChunkSource) Calls() int {
+	return int(atomic.LoadInt32(&cs.calls))
+}
@@ -0,0 +1,234 @@
+package piio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// errMmapClosed is returned when using a closed
+// MmapChunkSource.
+var errMmapClosed = errors.New("the memory mapping is closed")
+
+// Advice is a hint about how the digits of a
+// MmapChunkSource are going to be accessed.
+type Advice int
+
+const (
+	// AdviceNormal requests the default behaviour.
+	AdviceNormal Advice = iota
+	// AdviceSequential announces sequential access, so
+	// the kernel reads ahead aggressively.
+	AdviceSequential
+	// AdviceRandom announces random access, so the kernel
+	// does not read ahead.
+	AdviceRandom
+	// AdviceWillNeed announces that the whole file is going
+	// to be needed soon, so the kernel starts reading it.
+	AdviceWillNeed
+)
+
+var adviceNames = map[Advice]string{
+	AdviceNormal:     "normal",
+	AdviceSequential: "sequential",
+	AdviceRandom:     "random",
+	AdviceWillNeed:   "willneed",
+}
+
+// ParseAdvice returns the Advice with the given name.
+// See Advice.String for the names.
+func ParseAdvice(name string) (Advice, error) {
+	for advice, n := range adviceNames {
+		if n == name {
+			return advice, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown advice \"%s\"", name)
+}
+
+// String returns the name of the advice, i.e. one of
+// "normal", "sequential", "random" or "willneed".
+func (a Advice) String() string {
+	name, ok := adviceNames[a]
+	if !ok {
+		return fmt.Sprintf("Advice(%d)", int(a))
+	}
+	return name
+}
+
+// MmapChunkSource is a ChunkSource backed by a read only
+// memory mapping of a whole compressed or dense file. The
+// chunks it returns point directly into the mapping, so
+// accessing them after the source is closed crashes the
+// program. The source must only be closed once none of its
+// chunks are referenced anymore.
+type MmapChunkSource struct {
+	// mutex guards the mapping against being unmapped while
+	// it is in use.
+	mutex      sync.RWMutex
+	mapping    []byte
+	data       []byte
+	format     FileFormat
+	digitCount int64
+	maxSize    int
+}
+
+// NewMmapChunkSource maps the given file into memory. Only
+// FileFormatCompressed and FileFormatDense are supported.
+// Files with a header are validated.
+func NewMmapChunkSource(filename string, format FileFormat, maxSize int) (*MmapChunkSource, error) {
+	if format != FileFormatCompressed && format != FileFormatDense {
+		return nil, &UnsupportedFormatError{fmt.Sprintf("memory mapping is not supported for file format %s", format)}
+	}
+
+	err := ValidateFile(filename, format)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	h, err := readHeader(file)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() == 0 {
+		return nil, errors.New("cannot map an empty file")
+	}
+
+	mapping, err := mmapFile(file, int(fi.Size()))
+	if err != nil {
+		return nil, err
+	}
+
+	cs := &MmapChunkSource{
+		mapping: mapping,
+		data:    mapping,
+		format:  format,
+		maxSize: maxSize,
+	}
+	if h != nil {
+		cs.data = mapping[h.DataOffset:]
+		cs.digitCount = h.DigitCount
+	} else if format == FileFormatCompressed {
+		cs.digitCount = int64(len(cs.data)) * 2
+	} else {
+		cs.digitCount = int64(len(cs.data)) * 8 / denseGroupBits * denseGroupDigits
+	}
+
+	return cs, nil
+}
+
+// GetChunk returns the requested chunk without copying
+// any data. The digits are validated like by the readers.
+func (cs *MmapChunkSource) GetChunk(firstIndex int64, size int) (Chunk, error) {
+	err := checkChunkRequest(firstIndex, size, cs.maxSize)
+	if err != nil {
+		return nil, err
+	}
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+	if cs.mapping == nil {
+		return nil, errMmapClosed
+	}
+
+	length, err := limitSize(&Header{DigitCount: cs.digitCount}, firstIndex, size)
+	if err == io.EOF {
+		return nil, outOfRange(cs, firstIndex, size)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var chnk Chunk
+	if cs.format == FileFormatDense {
+		offset := firstIndex / DenseBlockDigits * DenseBlockBytes
+		chnk = denseChunkAt(cs.data[offset:], firstIndex, length)
+	} else {
+		chnk = compressedChunkAt(cs.data[firstIndex/2:], firstIndex, length)
+	}
+	err = validateChunk(chnk)
+	if err != nil {
+		return nil, err
+	}
+	return chnk, nil
+}
+
+// GetChunkContext does the same as GetChunk. As no data is
+// read, the context is only checked once.
+func (cs *MmapChunkSource) GetChunkContext(ctx context.Context, firstIndex int64, size int) (Chunk, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cs.GetChunk(firstIndex, size)
+}
+
+// AvailableDigits returns the amount of digits
+// available.
+func (cs *MmapChunkSource) AvailableDigits() (int64, error) {
+	return cs.digitCount, nil
+}
+
+// MaximumChunkSize returns the maximum allowed
+// chunk size.
+func (cs *MmapChunkSource) MaximumChunkSize() int {
+	return cs.maxSize
+}
+
+// Advise passes the given hint about the access pattern
+// of the whole file on to the kernel.
+func (cs *MmapChunkSource) Advise(advice Advice) error {
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+	if cs.mapping == nil {
+		return errMmapClosed
+	}
+	return madvise(cs.mapping, advice)
+}
+
+// Lock locks the pages holding the first digits of the
+// file in RAM, so they are never paged out.
+func (cs *MmapChunkSource) Lock(digits int64) error {
+	if digits > cs.digitCount {
+		digits = cs.digitCount
+	}
+	var size int64
+	if cs.format == FileFormatDense {
+		size = digits/DenseBlockDigits*DenseBlockBytes + int64(denseDataSize(int(digits%DenseBlockDigits)))
+	} else {
+		size = (digits + 1) / 2
+	}
+	cs.mutex.RLock()
+	defer cs.mutex.RUnlock()
+	if cs.mapping == nil {
+		return errMmapClosed
+	}
+	offset := int64(len(cs.mapping) - len(cs.data))
+	return mlock(cs.mapping[:offset+size])
+}
+
+// Close unmaps the file. Neither the source nor any chunk
+// returned by it may be used afterwards. It waits for
+// requests in progress to finish.
+func (cs *MmapChunkSource) Close() error {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	if cs.mapping == nil {
+		return nil
+	}
+	err := munmap(cs.mapping)
+	cs.mapping = nil
+	cs.data = nil
+	return err
+}
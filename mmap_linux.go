@@ -0,0 +1,33 @@
+package piio
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func mmapFile(file *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(file.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmap(mapping []byte) error {
+	return syscall.Munmap(mapping)
+}
+
+func madvise(mapping []byte, advice Advice) error {
+	switch advice {
+	case AdviceNormal:
+		return syscall.Madvise(mapping, syscall.MADV_NORMAL)
+	case AdviceSequential:
+		return syscall.Madvise(mapping, syscall.MADV_SEQUENTIAL)
+	case AdviceRandom:
+		return syscall.Madvise(mapping, syscall.MADV_RANDOM)
+	case AdviceWillNeed:
+		return syscall.Madvise(mapping, syscall.MADV_WILLNEED)
+	}
+	return fmt.Errorf("unknown advice %s", advice)
+}
+
+func mlock(mapping []byte) error {
+	return syscall.Mlock(mapping)
+}
@@ -0,0 +1,116 @@
+package piio
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMmapChunkSource(t *testing.T) {
+	Convey("Given a mapped compressed file with a header", t, func() {
+		filename, err := writeHeaderedFile(FileFormatCompressed, uncompressedPi)
+		So(err, ShouldBeNil)
+		defer os.Remove(filename)
+
+		cs, err := NewMmapChunkSource(filename, FileFormatCompressed, 8)
+		So(err, ShouldBeNil)
+		defer cs.Close()
+
+		Convey("the available digits should be read from the header.", func() {
+			avail, err := cs.AvailableDigits()
+			So(err, ShouldBeNil)
+			So(avail, ShouldEqual, 12)
+		})
+		Convey("chunks should point into the mapping.", func() {
+			c, err := cs.GetChunk(4, 8)
+			So(err, ShouldBeNil)
+			So(c.(*CompressedChunk).data, ShouldResemble, compressedPi[2:])
+			So(&c.(*CompressedChunk).data[0], ShouldEqual, &cs.data[2])
+		})
+		Convey("requests beyond the end should be trimmed.", func() {
+			c, err := cs.GetChunk(10, 8)
+			So(err, ShouldBeNil)
+			So(c.Length(), ShouldEqual, 2)
+
+			_, err = cs.GetChunk(12, 2)
+			So(errors.Is(err, ErrOutOfRange), ShouldBeTrue)
+			var rangeErr *OutOfRangeError
+			So(errors.As(err, &rangeErr), ShouldBeTrue)
+			So(rangeErr.Requested, ShouldResemble, Range{FirstIndex: 12, Size: 2})
+		})
+		Convey("advice and locking should work.", func() {
+			So(cs.Advise(AdviceRandom), ShouldBeNil)
+			So(cs.Lock(4), ShouldBeNil)
+		})
+		Convey("closing should invalidate the source.", func() {
+			So(cs.Close(), ShouldBeNil)
+			_, err := cs.GetChunk(0, 2)
+			So(err, ShouldNotBeNil)
+			So(cs.Advise(AdviceRandom), ShouldNotBeNil)
+			So(cs.Lock(4), ShouldNotBeNil)
+			So(cs.Close(), ShouldBeNil)
+		})
+		Convey("closing should wait for concurrent requests.", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for {
+						_, err := cs.GetChunk(0, 8)
+						if err != nil {
+							return
+						}
+					}
+				}()
+			}
+			So(cs.Close(), ShouldBeNil)
+			wg.Wait()
+		})
+	})
+	Convey("Given a mapped dense file", t, func() {
+		filename, err := writeTempFile(densePi)
+		So(err, ShouldBeNil)
+		defer os.Remove(filename)
+
+		cs, err := NewMmapChunkSource(filename, FileFormatDense, 12)
+		So(err, ShouldBeNil)
+		defer cs.Close()
+
+		Convey("reading should work.", func() {
+			c, err := cs.GetChunk(0, 12)
+			So(err, ShouldBeNil)
+			So(Decompress(c).(*UncompressedChunk).Digits, ShouldResemble, uncompressedPi)
+		})
+	})
+	Convey("Given a text file", t, func() {
+		filename, err := writeTempFile([]byte(textPi))
+		So(err, ShouldBeNil)
+		defer os.Remove(filename)
+
+		Convey("mapping it should be rejected.", func() {
+			_, err := NewMmapChunkSource(filename, FileFormatText, 12)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestCloseMmap(t *testing.T) {
+	Convey("Given a cached, mapped file opened with fallback", t, func() {
+		filename, err := writeTempFile(compressedPi)
+		So(err, ShouldBeNil)
+		defer os.Remove(filename)
+
+		cs, err := Open(filename, WithFormat(FileFormatCompressed), WithMmap(AdviceNormal), WithCache(1<<20), WithComputedDigits(20, 8, 0))
+		So(err, ShouldBeNil)
+
+		Convey("closing it should unmap the file.", func() {
+			So(Close(cs), ShouldBeNil)
+			cached := cs.(*FallbackChunkSource).Stored().(*CachedChunkSource)
+			So(cached.source.(*MmapChunkSource).mapping, ShouldBeNil)
+		})
+	})
+}
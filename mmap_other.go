@@ -0,0 +1,27 @@
+//go:build !linux
+// +build !linux
+
+package piio
+
+import (
+	"errors"
+	"os"
+)
+
+var errMmapUnsupported = errors.New("memory mapping is not supported on this platform")
+
+func mmapFile(file *os.File, size int) ([]byte, error) {
+	return nil, errMmapUnsupported
+}
+
+func munmap(mapping []byte) error {
+	return errMmapUnsupported
+}
+
+func madvise(mapping []byte, advice Advice) error {
+	return errMmapUnsupported
+}
+
+func mlock(mapping []byte) error {
+	return errMmapUnsupported
+}
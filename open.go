@@ -0,0 +1,295 @@
+package piio
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultMaximumChunkSize is the maximum chunk size of the
+// ChunkSources returned by Open unless configured otherwise.
+const DefaultMaximumChunkSize = 512
+
+// detectionSampleSize is the amount of bytes inspected
+// when detecting the format of a file.
+const detectionSampleSize = 4096
+
+var ycdMagic = []byte("#Compressed Digit File")
+
+type openOptions struct {
+	maxChunkSize int
+	format       FileFormat
+	formatSet    bool
+	cacheSize    int64
+	mmap         bool
+	advice       Advice
+	lockDigits   int64
+
+	computeLimit   int64
+	computeMaxSize int
+	computeTimeout time.Duration
+}
+
+// Option configures the ChunkSource returned by Open.
+type Option func(o *openOptions)
+
+// WithMaximumChunkSize sets the maximum chunk size of
+// the ChunkSource.
+func WithMaximumChunkSize(size int) Option {
+	return func(o *openOptions) {
+		o.maxChunkSize = size
+	}
+}
+
+// WithFormat disables the detection of the file format
+// and uses the given one instead.
+func WithFormat(format FileFormat) Option {
+	return func(o *openOptions) {
+		o.format = format
+		o.formatSet = true
+	}
+}
+
+// WithCache wraps the ChunkSource in a CachedChunkSource
+// using up to size bytes of memory. A size of 0 disables
+// the cache.
+func WithCache(size int64) Option {
+	return func(o *openOptions) {
+		o.cacheSize = size
+	}
+}
+
+// WithMmap uses a MmapChunkSource with the given advice
+// instead of reading the file on every request. This is
+// only supported for compressed and dense files.
+func WithMmap(advice Advice) Option {
+	return func(o *openOptions) {
+		o.mmap = true
+		o.advice = advice
+	}
+}
+
+// WithMlock locks the given amount of digits at the start
+// of the file in RAM. It only has an effect in combination
+// with WithMmap.
+func WithMlock(digits int64) Option {
+	return func(o *openOptions) {
+		o.lockDigits = digits
+	}
+}
+
+// WithComputedDigits computes the digits following the ones
+// of the file on demand, see NewFallbackChunkSource and
+// NewComputedChunkSource. At most maxSize digits are computed
+// per request and only the digits in front of limit are
+// available. A limit of 0 disables the computation.
+func WithComputedDigits(limit int64, maxSize int, timeout time.Duration) Option {
+	return func(o *openOptions) {
+		o.computeLimit = limit
+		o.computeMaxSize = maxSize
+		o.computeTimeout = timeout
+	}
+}
+
+// Open returns a ChunkSource reading the digits of pi from
+// the file at the given path. Unless the format is given via
+// WithFormat it is detected using DetectFormat. Files with a
+// header are validated, but their checksum is not verified.
+func Open(path string, opts ...Option) (ChunkSource, error) {
+	o := &openOptions{
+		maxChunkSize: DefaultMaximumChunkSize,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	format := o.format
+	if !o.formatSet {
+		var err error
+		format, err = DetectFormat(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The underlying source has to support whole blocks.
+	maxChunkSize := o.maxChunkSize
+	if o.cacheSize > 0 && maxChunkSize < DefaultCacheBlockSize {
+		maxChunkSize = DefaultCacheBlockSize
+	}
+
+	var source ChunkSource
+	switch format {
+	case FileFormatYCD:
+		var err error
+		source, err = NewYCDChunkSource(path, maxChunkSize)
+		if err != nil {
+			return nil, err
+		}
+
+	case FileFormatCompressed, FileFormatText, FileFormatDense:
+		if o.mmap {
+			var err error
+			source, err = openMmap(path, format, maxChunkSize, o)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+		err := ValidateFile(path, format)
+		if err != nil {
+			return nil, err
+		}
+		source = NewUncachedChunkSource(path, format, maxChunkSize)
+
+	default:
+		return nil, errUnknownFileFormat
+	}
+
+	if o.cacheSize > 0 {
+		source = NewCachedChunkSource(source, o.maxChunkSize, DefaultCacheBlockSize, o.cacheSize)
+	}
+	if o.computeLimit > 0 {
+		source = NewFallbackChunkSource(source, NewComputedChunkSource(o.computeMaxSize, o.computeLimit, o.computeTimeout))
+	}
+	return source, nil
+}
+
+// Close releases the resources held by a ChunkSource
+// returned by Open, like the memory mapping of WithMmap. No
+// chunk returned by the source may be used afterwards.
+func Close(cs ChunkSource) error {
+	switch c := cs.(type) {
+	case *CachedChunkSource:
+		return Close(c.source)
+	case *FallbackChunkSource:
+		return Close(c.stored)
+	case *contextChunkSource:
+		return Close(c.ChunkSource)
+	case io.Closer:
+		return c.Close()
+	}
+	return nil
+}
+
+func openMmap(path string, format FileFormat, maxChunkSize int, o *openOptions) (ChunkSource, error) {
+	cs, err := NewMmapChunkSource(path, format, maxChunkSize)
+	if err != nil {
+		return nil, err
+	}
+	err = cs.Advise(o.advice)
+	if err == nil && o.lockDigits > 0 {
+		err = cs.Lock(o.lockDigits)
+	}
+	if err != nil {
+		cs.Close()
+		return nil, err
+	}
+	return cs, nil
+}
+
+// DetectFormat sniffs the format of the file at the given
+// path. Files with a header are reported in the format stated
+// by it. Otherwise the format is guessed from the start of
+// the file, checking for .ycd files, ASCII digits with an
+// optional decimal point and trailing whitespace, valid
+// nibbles and valid groups of densely packed digits in that
+// order. Text files with whitespace between the digits are
+// rejected.
+func DetectFormat(path string) (FileFormat, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	h, err := ReadHeader(file)
+	if err == nil {
+		return h.Format, nil
+	} else if err != ErrNoHeader {
+		return 0, err
+	}
+
+	_, err = file.Seek(0, 0)
+	if err != nil {
+		return 0, err
+	}
+	sample := make([]byte, detectionSampleSize)
+	n, err := io.ReadFull(file, sample)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return 0, err
+	}
+	sample = sample[:n]
+	if n == 0 {
+		return 0, &UnsupportedFormatError{"could not detect the format of an empty file"}
+	}
+
+	text, wrapped := scanText(sample)
+	switch {
+	case bytes.HasPrefix(sample, ycdMagic) || strings.HasSuffix(path, ycdExtension):
+		return FileFormatYCD, nil
+	case wrapped:
+		return 0, &UnsupportedFormatError{"text files with whitespace between the digits are not supported"}
+	case text:
+		return FileFormatText, nil
+	case isCompressed(sample):
+		return FileFormatCompressed, nil
+	case isDense(sample):
+		return FileFormatDense, nil
+	}
+	return 0, &UnsupportedFormatError{"could not detect the file format"}
+}
+
+// scanText reports whether the sample consists of digits
+// followed by whitespace. Samples with whitespace between the
+// digits, like line-wrapped files, are reported as wrapped as
+// the text readers expect one character per digit.
+func scanText(sample []byte) (text, wrapped bool) {
+	digits := 0
+	space := false
+	for i, b := range sample {
+		switch {
+		case b == '\n' || b == '\r' || b == ' ' || b == '\t':
+			space = true
+		case '0' <= b && b <= '9':
+			if space {
+				wrapped = true
+			}
+			digits++
+		case b == '.' && i > 0 && i == digits:
+			// Decimal point after the integer digits.
+		default:
+			return false, false
+		}
+	}
+	return digits > 0 && !wrapped, wrapped
+}
+
+func isCompressed(sample []byte) bool {
+	for _, b := range sample {
+		if b>>4 > 9 || b&0x0F > 9 {
+			return false
+		}
+	}
+	return true
+}
+
+func isDense(sample []byte) bool {
+	blocks := len(sample) / DenseBlockBytes
+	if blocks == 0 {
+		return false
+	}
+	chunk := &DenseChunk{
+		length: blocks * DenseBlockDigits,
+		data:   sample[:blocks*DenseBlockBytes],
+	}
+	for g := 0; g*denseGroupDigits < chunk.length; g++ {
+		_, ok := chunk.group(g)
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package piio
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func writeTempFile(data []byte) (string, error) {
+	file, err := ioutil.TempFile("", "piio")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	_, err = file.Write(data)
+	return file.Name(), err
+}
+
+func TestDetectFormat(t *testing.T) {
+	Convey("Given files of different formats", t, func() {
+		files := map[string]FileFormat{}
+		for data, format := range map[string]FileFormat{
+			textPi:               FileFormatText,
+			"3.14159265359\n":    FileFormatText,
+			string(compressedPi): FileFormatCompressed,
+			string(densePi):      FileFormatDense,
+		} {
+			filename, err := writeTempFile([]byte(data))
+			So(err, ShouldBeNil)
+			defer os.Remove(filename)
+			files[filename] = format
+		}
+		headered, err := writeHeaderedFile(FileFormatText, uncompressedPi)
+		So(err, ShouldBeNil)
+		defer os.Remove(headered)
+		files[headered] = FileFormatText
+
+		Convey("the formats should be detected.", func() {
+			for filename, format := range files {
+				detected, err := DetectFormat(filename)
+				So(err, ShouldBeNil)
+				So(detected, ShouldEqual, format)
+			}
+		})
+	})
+	Convey("Given a .ycd file", t, func() {
+		dir, err := ioutil.TempDir("", "piio")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		So(writeYCDFile(dir, 0, 50, 50, piDecimals), ShouldBeNil)
+
+		Convey("the format should be detected.", func() {
+			format, err := DetectFormat(filepath.Join(dir, "Pi - Dec - Chudnovsky - 0.ycd"))
+			So(err, ShouldBeNil)
+			So(format, ShouldEqual, FileFormatYCD)
+		})
+	})
+	Convey("Given an empty file", t, func() {
+		filename, err := writeTempFile(nil)
+		So(err, ShouldBeNil)
+		defer os.Remove(filename)
+
+		Convey("detection should fail.", func() {
+			_, err := DetectFormat(filename)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestOpen(t *testing.T) {
+	Convey("Given a text file with a decimal point", t, func() {
+		filename, err := writeTempFile([]byte("3.14159265359"))
+		So(err, ShouldBeNil)
+		defer os.Remove(filename)
+
+		cs, err := Open(filename, WithMaximumChunkSize(8))
+		So(err, ShouldBeNil)
+
+		Convey("the options should be applied.", func() {
+			So(cs.MaximumChunkSize(), ShouldEqual, 8)
+		})
+		Convey("the decimal point should not count as a digit.", func() {
+			avail, err := cs.AvailableDigits()
+			So(err, ShouldBeNil)
+			So(avail, ShouldEqual, 12)
+
+			c, err := cs.GetChunk(0, 4)
+			So(err, ShouldBeNil)
+			So(c.(*UncompressedChunk).Digits, ShouldResemble, uncompressedPi[:4])
+
+			c, err = cs.GetChunk(4, 8)
+			So(err, ShouldBeNil)
+			So(c.(*UncompressedChunk).Digits, ShouldResemble, uncompressedPi[4:])
+		})
+	})
+	Convey("Given a text file ending in whitespace", t, func() {
+		filename, err := writeTempFile([]byte("3.14159265359 \r\n"))
+		So(err, ShouldBeNil)
+		defer os.Remove(filename)
+
+		cs, err := Open(filename, WithFormat(FileFormatText))
+		So(err, ShouldBeNil)
+
+		Convey("the whitespace should not count as digits.", func() {
+			avail, err := cs.AvailableDigits()
+			So(err, ShouldBeNil)
+			So(avail, ShouldEqual, 12)
+		})
+	})
+	Convey("Given a line-wrapped text file", t, func() {
+		filename, err := writeTempFile([]byte("3.14159\n26535\n89793\n"))
+		So(err, ShouldBeNil)
+		defer os.Remove(filename)
+
+		Convey("opening it should fail instead of misreading the digits.", func() {
+			_, err := Open(filename)
+			var formatErr *UnsupportedFormatError
+			So(errors.As(err, &formatErr), ShouldBeTrue)
+		})
+	})
+	Convey("Given a file opened with an explicit format", t, func() {
+		filename, err := writeTempFile(compressedPi)
+		So(err, ShouldBeNil)
+		defer os.Remove(filename)
+
+		cs, err := Open(filename, WithFormat(FileFormatText))
+		So(err, ShouldBeNil)
+
+		Convey("the format should not be detected.", func() {
+			c, err := cs.GetChunk(0, 2)
+			So(err, ShouldBeNil)
+			So(c.(*UncompressedChunk).Digits, ShouldResemble, []byte{1})
+		})
+	})
+}
+
+func TestParseFileFormat(t *testing.T) {
+	Convey("Parsing the name of any file format should work.", t, func() {
+		for _, format := range []FileFormat{FileFormatCompressed, FileFormatText, FileFormatDense, FileFormatYCD} {
+			parsed, err := ParseFileFormat(format.String())
+			So(err, ShouldBeNil)
+			So(parsed, ShouldEqual, format)
+		}
+		_, err := ParseFileFormat("unknown")
+		So(err, ShouldNotBeNil)
+	})
+}
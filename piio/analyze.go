@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/targodan/piio"
+	"github.com/targodan/piio/analyze"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// analyzeBlockSize is the amount of digits read at once by
+// the analyze command.
+const analyzeBlockSize = 1 << 20
+
+var analyzeCommand = cli.Command{
+	Name:      "analyze",
+	Usage:     "runs statistical tests of randomness over a range of digits of pi and prints the results as JSON",
+	ArgsUsage: "<start> <length>",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "pi,p",
+			Usage: "The file of pi.",
+			Value: "pi.bin",
+		},
+		cli.StringFlag{
+			Name:  "format,f",
+			Usage: "The format of the file of pi. One of auto, compressed, text, dense or ycd.",
+			Value: "auto",
+		},
+		cli.BoolFlag{
+			Name:  "quiet,q",
+			Usage: "Do not display the progress on stderr.",
+		},
+	},
+	Action: analyzeAction,
+}
+
+func analyzeAction(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return cli.NewExitError("expected exactly two arguments usage: piio analyze <start> <length>", 1)
+	}
+	start, err := strconv.ParseInt(c.Args().Get(0), 10, 64)
+	if err != nil {
+		return cli.NewExitError("the start must be a number, got "+c.Args().Get(0), 1)
+	}
+	length, err := strconv.ParseInt(c.Args().Get(1), 10, 64)
+	if err != nil {
+		return cli.NewExitError("the length must be a number, got "+c.Args().Get(1), 1)
+	}
+	if start < 0 || length <= 0 {
+		return cli.NewExitError("the start must not be negative and the length must be positive", 1)
+	}
+
+	cs, err := openIndexSource(c.String("pi"), c.String("format"), analyzeBlockSize)
+	if err != nil {
+		return err
+	}
+	avail, err := cs.AvailableDigits()
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if start+length > avail {
+		return cli.NewExitError(&piio.OutOfRangeError{
+			Requested: piio.Range{FirstIndex: start, Size: length},
+			Available: piio.Range{FirstIndex: 0, Size: avail},
+		}, 2)
+	}
+
+	var r io.Reader = piio.NewChunkSourceReader(cs, start, length)
+	if !c.Bool("quiet") && isTerminal(os.Stderr) {
+		p := &progress{
+			action: "analyzed",
+			total:  length,
+			w:      os.Stderr,
+		}
+		r = io.TeeReader(r, p)
+		defer p.Done()
+	}
+
+	a := analyze.NewAnalyzer(start)
+	_, err = io.Copy(a, r)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(a.Report())
+	if err != nil {
+		return cli.NewExitError(err, 3)
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+
+	"github.com/targodan/piio"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// convertFlags are the flags shared by the convert and
+// decompress commands.
+var convertFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "from",
+		Usage: "The format of the input. One of auto, compressed, text, dense or ycd. Input from stdin is read as text unless it has a header.",
+		Value: "auto",
+	},
+	cli.BoolFlag{
+		Name:  "no-verify",
+		Usage: "Do not verify the output by reading it back.",
+	},
+	cli.BoolFlag{
+		Name:  "quiet,q",
+		Usage: "Do not display the progress on stderr.",
+	},
+}
+
+var convertCommand = cli.Command{
+	Name:      "convert",
+	Usage:     "converts a file of digits of pi to another format",
+	ArgsUsage: "<infile> <outfile>",
+	Flags: append([]cli.Flag{
+		cli.StringFlag{
+			Name:  "to",
+			Usage: "The format of the output. One of compressed, text or dense.",
+			Value: "compressed",
+		},
+		cli.BoolFlag{
+			Name:  "raw,r",
+			Usage: "Write the digits without a header. Required when writing to a pipe.",
+		},
+	}, convertFlags...),
+	Action: convertAction,
+}
+
+var decompressCommand = cli.Command{
+	Name:      "decompress",
+	Usage:     "decompresses a file of digits of pi to text, the same as convert --to text --raw",
+	ArgsUsage: "<infile> <outfile>",
+	Flags:     convertFlags,
+	Action:    convertAction,
+}
+
+func convertAction(c *cli.Context) error {
+	infile := c.Args().Get(0)
+	outfile := c.Args().Get(1)
+
+	if infile == "" || outfile == "" {
+		return cli.NewExitError("expected exactly two arguments usage: piio "+c.Command.Name+" <infile> <outfile>", 1)
+	}
+
+	to := piio.FileFormatText
+	raw := true
+	if c.Command.Name == "convert" {
+		var err error
+		to, err = piio.ParseFileFormat(c.String("to"))
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		raw = c.Bool("raw")
+	}
+
+	in, total, err := openDigits(infile, c.String("from"))
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	defer in.Close()
+
+	out, err := createOutput(outfile)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	defer out.Close()
+
+	cw, err := newCompressWriter(out, to, raw)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+
+	crc := crc32.NewIEEE()
+	var digits io.Reader = io.TeeReader(in, crc)
+	if !c.Bool("quiet") && isTerminal(os.Stderr) {
+		p := &progress{
+			action: "converted",
+			total:  total,
+			w:      os.Stderr,
+		}
+		defer p.Done()
+		digits = io.TeeReader(digits, p)
+	}
+
+	_, err = io.Copy(cw, digits)
+	if err != nil {
+		return cli.NewExitError(err, 3)
+	}
+	err = cw.Close()
+	if err != nil {
+		return cli.NewExitError(err, 3)
+	}
+
+	if c.Bool("no-verify") || outfile == "-" {
+		return nil
+	}
+	err = verifyOutput(outfile, to, cw.Digits(), crc.Sum32())
+	if err != nil {
+		return cli.NewExitError(err, 4)
+	}
+	return nil
+}
+
+// openDigits opens the given file, or stdin for "-", for
+// reading its digits as text. It also returns the amount of
+// digits in the file or -1 if it is unknown.
+func openDigits(filename, formatName string) (io.ReadCloser, int64, error) {
+	if filename == "-" {
+		format := piio.FileFormatText
+		if formatName != "auto" {
+			var err error
+			format, err = piio.ParseFileFormat(formatName)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+		dr, err := piio.NewDecompressReader(os.Stdin, format)
+		if err != nil {
+			return nil, 0, err
+		}
+		return readCloser{dr, os.Stdin.Close}, dr.DigitCount(), nil
+	}
+
+	format, err := fileFormat(formatName, filename)
+	if err != nil {
+		return nil, 0, err
+	}
+	if format == piio.FileFormatYCD {
+		cs, err := piio.Open(filename, piio.WithFormat(format), piio.WithMaximumChunkSize(1<<16))
+		if err != nil {
+			return nil, 0, err
+		}
+		total, err := cs.AvailableDigits()
+		if err != nil {
+			return nil, 0, err
+		}
+		return readCloser{piio.NewChunkSourceReader(cs, 0, -1), func() error { return nil }}, total, nil
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, err
+	}
+	dr, err := piio.NewDecompressReader(file, format)
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return readCloser{dr, file.Close}, dr.DigitCount(), nil
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (rc readCloser) Close() error {
+	return rc.close()
+}
+
+// createOutput creates the given file or returns stdout
+// for "-".
+func createOutput(filename string) (writeSeekCloser, error) {
+	if filename == "-" {
+		return os.Stdout, nil
+	}
+	return os.Create(filename)
+}
+
+// newCompressWriter creates a CompressWriter writing the
+// given format to out, with a header unless raw is set.
+func newCompressWriter(out io.WriteSeeker, format piio.FileFormat, raw bool) (*piio.CompressWriter, error) {
+	if raw {
+		return piio.NewCompressWriter(out, format)
+	}
+	fw, err := piio.NewFileWriter(out, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s, use --raw to write without a header", err.Error())
+	}
+	return piio.NewFileCompressWriter(fw), nil
+}
+
+// verifyOutput reads back the written file and compares the
+// digits with the checksum of the converted digits.
+func verifyOutput(filename string, format piio.FileFormat, digits int64, checksum uint32) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	dr, err := piio.NewDecompressReader(file, format)
+	if err != nil {
+		return err
+	}
+	crc := crc32.NewIEEE()
+	// Files without a header may contain padding digits.
+	n, err := io.Copy(crc, io.LimitReader(dr, digits))
+	if err != nil {
+		return err
+	}
+	if n != digits {
+		return fmt.Errorf("verification failed, wrote %d digits but read back %d", digits, n)
+	}
+	if crc.Sum32() != checksum {
+		return fmt.Errorf("verification failed, the digits read back differ from the input")
+	}
+	return nil
+}
+
+// isTerminal returns whether the file is a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// progress displays the amount of processed digits,
+// updating the display at most once per second.
+type progress struct {
+	action string
+	total  int64
+	done   int64
+	last   time.Time
+	w      io.Writer
+}
+
+func (p *progress) Write(digits []byte) (int, error) {
+	p.done += int64(len(digits))
+	if time.Since(p.last) >= time.Second {
+		p.print()
+	}
+	return len(digits), nil
+}
+
+func (p *progress) print() {
+	p.last = time.Now()
+	if p.total > 0 {
+		fmt.Fprintf(p.w, "\r%s %d of %d digits (%.1f%%)", p.action, p.done, p.total, float64(p.done)*100/float64(p.total))
+	} else {
+		fmt.Fprintf(p.w, "\r%s %d digits", p.action, p.done)
+	}
+}
+
+// Done prints the final state.
+func (p *progress) Done() {
+	p.print()
+	fmt.Fprintln(p.w)
+}
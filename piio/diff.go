@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"github.com/targodan/piio"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var diffCommand = cli.Command{
+	Name:      "diff",
+	Usage:     "compares the digits of two files of pi",
+	ArgsUsage: "<file a> <file b>",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "format-a",
+			Usage: "The format of the first file. One of auto, compressed, text, dense or ycd.",
+			Value: "auto",
+		},
+		cli.StringFlag{
+			Name:  "format-b",
+			Usage: "The format of the second file. One of auto, compressed, text, dense or ycd.",
+			Value: "auto",
+		},
+		cli.IntFlag{
+			Name:  "workers,w",
+			Usage: "The amount of blocks compared in parallel.",
+			Value: runtime.NumCPU(),
+		},
+	},
+	Action: diffAction,
+}
+
+func diffAction(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return cli.NewExitError("expected exactly two arguments usage: piio diff <file a> <file b>", 1)
+	}
+	a, err := openDiffSource(c.Args().Get(0), c.String("format-a"))
+	if err != nil {
+		return err
+	}
+	b, err := openDiffSource(c.Args().Get(1), c.String("format-b"))
+	if err != nil {
+		return err
+	}
+
+	result, err := piio.Diff(context.Background(), a, b, piio.DefaultDiffBlockSize, c.Int("workers"))
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+
+	if result.FirstMismatch >= 0 {
+		fmt.Printf("first mismatch at index %d\n", result.FirstMismatch)
+	} else {
+		fmt.Println("no mismatches")
+	}
+	fmt.Printf("mismatches: %d\n", result.Mismatches)
+	fmt.Printf("length: %d and %d digits, difference %d\n", result.LengthA, result.LengthB, result.LengthDifference())
+
+	// Like diff(1), differences are reported with exit code 1.
+	if !result.Equal() {
+		return cli.NewExitError("", 1)
+	}
+	return nil
+}
+
+func openDiffSource(filename, formatName string) (piio.ChunkSource, error) {
+	format, err := fileFormat(formatName, filename)
+	if err != nil {
+		return nil, cli.NewExitError(err, 2)
+	}
+	cs, err := piio.Open(filename, piio.WithFormat(format), piio.WithMaximumChunkSize(piio.DefaultDiffBlockSize))
+	if err != nil {
+		return nil, cli.NewExitError(err, 2)
+	}
+	return cs, nil
+}
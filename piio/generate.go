@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/targodan/piio"
+	"github.com/targodan/piio/chudnovsky"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var generateCommand = cli.Command{
+	Name:  "generate",
+	Usage: "computes digits of pi using the Chudnovsky series",
+	Flags: []cli.Flag{
+		cli.IntFlag{
+			Name:  "digits,n",
+			Usage: "The amount of digits to compute, including the 3.",
+			Value: 1000000,
+		},
+		cli.StringFlag{
+			Name:  "out,o",
+			Usage: "The file to write the digits to.",
+			Value: "pi.bin",
+		},
+		cli.StringFlag{
+			Name:  "format,f",
+			Usage: "The format of the output. One of compressed, text or dense.",
+			Value: "compressed",
+		},
+		cli.BoolFlag{
+			Name:  "raw,r",
+			Usage: "Write the digits without a header. Required when writing to a pipe.",
+		},
+		cli.IntFlag{
+			Name:  "workers,w",
+			Usage: "The amount of goroutines used. Defaults to the number of CPUs.",
+		},
+		cli.StringFlag{
+			Name:  "checkpoint",
+			Usage: "Store intermediate results in the given directory, so an interrupted run can be resumed by running the same command again.",
+		},
+		cli.BoolFlag{
+			Name:  "quiet,q",
+			Usage: "Do not display the progress on stderr.",
+		},
+	},
+	Action: generateAction,
+}
+
+func generateAction(c *cli.Context) error {
+	format, err := piio.ParseFileFormat(c.String("format"))
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	cfg := chudnovsky.Config{
+		Workers:       c.Int("workers"),
+		CheckpointDir: c.String("checkpoint"),
+	}
+	if !c.Bool("quiet") && isTerminal(os.Stderr) {
+		cfg.Progress = func(done, total int) {
+			fmt.Fprintf(os.Stderr, "\rcomputed %d of %d segments", done, total)
+			if done == total {
+				fmt.Fprintln(os.Stderr)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	digits, err := chudnovsky.Pi(ctx, c.Int("digits"), cfg)
+	if err != nil {
+		return cli.NewExitError(err, 3)
+	}
+
+	out, err := createOutput(c.String("out"))
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	defer out.Close()
+
+	cw, err := newCompressWriter(out, format, c.Bool("raw"))
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	_, err = cw.Write(digits)
+	if err == nil {
+		err = cw.Close()
+	}
+	if err != nil {
+		return cli.NewExitError(err, 3)
+	}
+	return nil
+}
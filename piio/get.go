@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/targodan/piio"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// getBlockSize is the amount of digits requested at once by
+// the get command.
+const getBlockSize = 1 << 16
+
+var getCommand = cli.Command{
+	Name:      "get",
+	Usage:     "prints a range of digits of pi from a local file",
+	ArgsUsage: "<start> <length>",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "pi,p",
+			Usage: "The file of pi.",
+			Value: "pi.bin",
+		},
+		cli.StringFlag{
+			Name:  "format,f",
+			Usage: "The format of the file of pi. One of auto, compressed, text, dense or ycd.",
+			Value: "auto",
+		},
+		cli.StringFlag{
+			Name:  "output,o",
+			Usage: "The output format. One of text, json or raw. Raw writes two digits per byte like the compressed format.",
+			Value: "text",
+		},
+	},
+	Action: getAction,
+}
+
+func getAction(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return cli.NewExitError("expected exactly two arguments usage: piio get <start> <length>", 1)
+	}
+	start, err := strconv.ParseInt(c.Args().Get(0), 10, 64)
+	if err != nil {
+		return cli.NewExitError("the start must be a number, got "+c.Args().Get(0), 1)
+	}
+	length, err := strconv.ParseInt(c.Args().Get(1), 10, 64)
+	if err != nil {
+		return cli.NewExitError("the length must be a number, got "+c.Args().Get(1), 1)
+	}
+	if start < 0 || length <= 0 {
+		return cli.NewExitError("the start must not be negative and the length must be positive", 1)
+	}
+
+	format, err := fileFormat(c.String("format"), c.String("pi"))
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	cs, err := piio.Open(c.String("pi"), piio.WithFormat(format), piio.WithMaximumChunkSize(getBlockSize))
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	defer piio.Close(cs)
+
+	// Check the range up front, so no partial output is written.
+	avail, err := cs.AvailableDigits()
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if start+length > avail {
+		return cli.NewExitError(&piio.OutOfRangeError{
+			Requested: piio.Range{FirstIndex: start, Size: length},
+			Available: piio.Range{FirstIndex: 0, Size: avail},
+		}, 2)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	digits := piio.NewChunkSourceReader(cs, start, length)
+	switch c.String("output") {
+	case "text":
+		_, err = io.Copy(out, digits)
+
+	case "json":
+		err = writeJSONDigits(out, start, digits)
+
+	case "raw":
+		err = writeRawDigits(out, digits)
+
+	default:
+		return cli.NewExitError(fmt.Sprintf("unknown output format \"%s\"", c.String("output")), 1)
+	}
+	if err == nil {
+		err = out.Flush()
+	}
+	if err != nil {
+		return cli.NewExitError(err, 3)
+	}
+	return nil
+}
+
+// writeRawDigits writes the text digits packed into two
+// digits per byte like the compressed format, but without a
+// header.
+func writeRawDigits(w io.Writer, digits io.Reader) error {
+	cw, err := piio.NewCompressWriter(w, piio.FileFormatCompressed)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(cw, digits)
+	if err != nil {
+		return err
+	}
+	return cw.Close()
+}
+
+// writeJSONDigits writes the text digits in the shape of a
+// rest.ChunkResponse without holding all of them in memory.
+func writeJSONDigits(w io.Writer, firstIndex int64, digits io.Reader) error {
+	_, err := fmt.Fprintf(w, "{\"firstIndex\":%d,\"digits\":[", firstIndex)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, getBlockSize)
+	encoded := make([]byte, 0, 2*getBlockSize)
+	first := true
+	for {
+		n, err := digits.Read(buf)
+		encoded = encoded[:0]
+		for _, d := range buf[:n] {
+			if !first {
+				encoded = append(encoded, ',')
+			}
+			encoded = append(encoded, d)
+			first = false
+		}
+		_, werr := w.Write(encoded)
+		if werr != nil {
+			return werr
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(w, "],\"error\":null}\n")
+	return err
+}
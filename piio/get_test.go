@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/targodan/piio"
+	"github.com/targodan/piio/rest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const getTestDigits = "14159265358979323846"
+
+func TestWriteJSONDigits(t *testing.T) {
+	Convey("Writing digits as JSON should", t, func() {
+		Convey("match the shape of a chunk response.", func() {
+			var buf bytes.Buffer
+			So(writeJSONDigits(&buf, 1, strings.NewReader(getTestDigits)), ShouldBeNil)
+
+			var resp rest.ChunkResponse
+			So(json.Unmarshal(buf.Bytes(), &resp), ShouldBeNil)
+			So(resp.FirstIndex, ShouldEqual, 1)
+			So(resp.Digits, ShouldResemble, []int{1, 4, 1, 5, 9, 2, 6, 5, 3, 5, 8, 9, 7, 9, 3, 2, 3, 8, 4, 6})
+			So(resp.Error, ShouldBeNil)
+		})
+		Convey("write an empty list without digits.", func() {
+			var buf bytes.Buffer
+			So(writeJSONDigits(&buf, 0, strings.NewReader("")), ShouldBeNil)
+
+			var resp rest.ChunkResponse
+			So(json.Unmarshal(buf.Bytes(), &resp), ShouldBeNil)
+			So(resp.Digits, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestWriteRawDigits(t *testing.T) {
+	Convey("Raw digits should be read back by the decompress reader", t, func() {
+		for _, digits := range []string{getTestDigits, getTestDigits[:7]} {
+			var buf bytes.Buffer
+			So(writeRawDigits(&buf, strings.NewReader(digits)), ShouldBeNil)
+			So(buf.Len(), ShouldEqual, (len(digits)+1)/2)
+
+			dr, err := piio.NewDecompressReader(&buf, piio.FileFormatCompressed)
+			So(err, ShouldBeNil)
+			text, err := ioutil.ReadAll(dr)
+			So(err, ShouldBeNil)
+			// Without a header the padding nibble of an odd
+			// amount of digits reads as a zero.
+			if len(digits)%2 == 1 {
+				digits += "0"
+			}
+			So(string(text), ShouldEqual, digits)
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/targodan/piio"
+	"github.com/targodan/piio/search"
+	"github.com/targodan/piio/stats"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// indexBlockSize is the amount of digits read at once while
+// building an index.
+const indexBlockSize = 1 << 20
+
+var indexCommand = cli.Command{
+	Name:  "index",
+	Usage: "builds indexes over a file of pi",
+	Subcommands: []cli.Command{
+		{
+			Name:  "build",
+			Usage: "builds a suffix array index for searching sequences of digits",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "pi,p",
+					Usage: "The file of pi.",
+					Value: "pi.bin",
+				},
+				cli.StringFlag{
+					Name:  "format,f",
+					Usage: "The format of the file of pi. One of auto, compressed, text, dense or ycd.",
+					Value: "auto",
+				},
+				cli.StringFlag{
+					Name:  "out,o",
+					Usage: "The file to write the index to.",
+					Value: "pi.idx",
+				},
+				cli.IntFlag{
+					Name:  "depth,d",
+					Usage: "The amount of leading digits by which the suffixes are sorted. Longer sequences are slower to search.",
+					Value: search.DefaultIndexDepth,
+				},
+			},
+			Action: indexBuildAction,
+		},
+		{
+			Name:  "first-occurrence",
+			Usage: "builds a table of the first occurrence of every sequence of n digits",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "pi,p",
+					Usage: "The file of pi.",
+					Value: "pi.bin",
+				},
+				cli.StringFlag{
+					Name:  "format,f",
+					Usage: "The format of the file of pi. One of auto, compressed, text, dense or ycd.",
+					Value: "auto",
+				},
+				cli.StringFlag{
+					Name:  "out,o",
+					Usage: "The file to write the table to. Defaults to pi-first-<n>.idx.",
+				},
+				cli.IntFlag{
+					Name:  "n",
+					Usage: "The length of the sequences. The table holds 10^n positions.",
+					Value: 6,
+				},
+			},
+			Action: indexFirstOccurrenceAction,
+		},
+		{
+			Name:  "stats",
+			Usage: "builds an index of the cumulative counts of the digits for computing statistics",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "pi,p",
+					Usage: "The file of pi.",
+					Value: "pi.bin",
+				},
+				cli.StringFlag{
+					Name:  "format,f",
+					Usage: "The format of the file of pi. One of auto, compressed, text, dense or ycd.",
+					Value: "auto",
+				},
+				cli.StringFlag{
+					Name:  "out,o",
+					Usage: "The file to write the index to.",
+					Value: "pi-stats.idx",
+				},
+				cli.Int64Flag{
+					Name:  "interval,k",
+					Usage: "The amount of digits between two entries of the index. At most this many digits are read per range.",
+					Value: stats.DefaultIndexInterval,
+				},
+			},
+			Action: indexStatsAction,
+		},
+	},
+}
+
+func indexBuildAction(c *cli.Context) error {
+	cs, err := openIndexSource(c.String("pi"), c.String("format"), indexBlockSize)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(c.String("out"))
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	err = search.BuildIndex(context.Background(), out, cs, c.Int("depth"))
+	if err == nil {
+		err = out.Close()
+	} else {
+		out.Close()
+	}
+	if err != nil {
+		os.Remove(c.String("out"))
+		return cli.NewExitError(err, 3)
+	}
+	return nil
+}
+
+func indexFirstOccurrenceAction(c *cli.Context) error {
+	cs, err := openIndexSource(c.String("pi"), c.String("format"), indexBlockSize)
+	if err != nil {
+		return err
+	}
+
+	filename := c.String("out")
+	if filename == "" {
+		filename = fmt.Sprintf("pi-first-%d.idx", c.Int("n"))
+	}
+	out, err := os.Create(filename)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	err = search.BuildFirstOccurrenceTable(context.Background(), out, cs, c.Int("n"))
+	if err == nil {
+		err = out.Close()
+	} else {
+		out.Close()
+	}
+	if err != nil {
+		os.Remove(filename)
+		return cli.NewExitError(err, 3)
+	}
+	return nil
+}
+
+func indexStatsAction(c *cli.Context) error {
+	cs, err := openIndexSource(c.String("pi"), c.String("format"), indexBlockSize)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(c.String("out"))
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	err = stats.BuildIndex(context.Background(), out, cs, c.Int64("interval"))
+	if err == nil {
+		err = out.Close()
+	} else {
+		out.Close()
+	}
+	if err != nil {
+		os.Remove(c.String("out"))
+		return cli.NewExitError(err, 3)
+	}
+	return nil
+}
+
+// openIndexSource opens the file of pi an index is built
+// from or used with.
+func openIndexSource(filename, formatName string, maxChunkSize int) (piio.ChunkSource, error) {
+	format, err := fileFormat(formatName, filename)
+	if err != nil {
+		return nil, cli.NewExitError(err, 1)
+	}
+	cs, err := piio.Open(filename, piio.WithFormat(format), piio.WithMaximumChunkSize(maxChunkSize))
+	if err != nil {
+		return nil, cli.NewExitError(err, 2)
+	}
+	return cs, nil
+}
@@ -1,23 +1,23 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/targodan/piio"
 	"github.com/targodan/piio/rest"
+	"github.com/targodan/piio/search"
+	"github.com/targodan/piio/stats"
 	"gopkg.in/urfave/cli.v1"
 )
 
 const defaultChunkSize = 512
 
-type readSeekCloser interface {
-	io.ReadSeeker
-	io.Closer
-}
 type writeSeekCloser interface {
 	io.WriteSeeker
 	io.Closer
@@ -30,10 +30,25 @@ func main() {
 
 	app.Commands = []cli.Command{
 		{
-			Name:   "compress",
-			Usage:  "compresses a text file of digits of pi",
+			Name:  "compress",
+			Usage: "compresses a text file of digits of pi",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "raw,r",
+					Usage: "Write the digits without a header. Required when writing to a pipe.",
+				},
+			},
 			Action: compressAction,
 		},
+		decompressCommand,
+		convertCommand,
+		getCommand,
+		generateCommand,
+		verifyCommand,
+		diffCommand,
+		indexCommand,
+		statsCommand,
+		analyzeCommand,
 		{
 			Name:  "serve",
 			Usage: "listen and serve",
@@ -53,6 +68,85 @@ func main() {
 					Usage: "The maximum size of a chunk to be served.",
 					Value: defaultChunkSize,
 				},
+				cli.StringFlag{
+					Name:  "format,f",
+					Usage: "The format of the file of pi. One of auto, compressed, text, dense or ycd.",
+					Value: "auto",
+				},
+				cli.IntFlag{
+					Name:  "cache-size",
+					Usage: "The size of the cache for blocks of digits in MiB. 0 disables the cache.",
+					Value: 0,
+				},
+				cli.StringFlag{
+					Name:  "mmap",
+					Usage: "Map the file of pi into memory with the given access advice. One of off, normal, sequential, random or willneed.",
+					Value: "off",
+				},
+				cli.Int64Flag{
+					Name:  "mlock",
+					Usage: "Lock the given amount of digits at the start of the file in RAM. Requires --mmap.",
+				},
+				cli.IntFlag{
+					Name:  "max-hex-size",
+					Usage: "The maximum amount of hexadecimal digits computed per request.",
+					Value: rest.DefaultMaximumHexSize,
+				},
+				cli.Int64Flag{
+					Name:  "compute-limit",
+					Usage: "Compute the digits past the end of the file of pi on demand, up to the given index. 0 disables the computation.",
+				},
+				cli.IntFlag{
+					Name:  "compute-max-size",
+					Usage: "The maximum amount of digits computed per request.",
+					Value: 64,
+				},
+				cli.DurationFlag{
+					Name:  "compute-timeout",
+					Usage: "The maximum time spent computing digits per request.",
+					Value: 3 * time.Second,
+				},
+				cli.Int64Flag{
+					Name:  "max-stream-length",
+					Usage: "The maximum amount of digits streamed per request.",
+					Value: rest.DefaultMaximumStreamLength,
+				},
+				cli.Int64Flag{
+					Name:  "max-batch-size",
+					Usage: "The maximum amount of digits requested per batch.",
+					Value: rest.DefaultMaximumBatchSize,
+				},
+				cli.DurationFlag{
+					Name:  "timeout",
+					Usage: "The maximum time spent on a request.",
+					Value: 5 * time.Second,
+				},
+				cli.DurationFlag{
+					Name:  "stream-timeout",
+					Usage: "The maximum time spent on streaming digits or the raw file.",
+					Value: 10 * time.Minute,
+				},
+				cli.StringFlag{
+					Name:  "index",
+					Usage: "The suffix array index used to search sequences of digits, see \"piio index build\". Without one the digits are scanned.",
+				},
+				cli.StringFlag{
+					Name:  "first-occurrence",
+					Usage: "The table used to look up the first occurrence of sequences, see \"piio index first-occurrence\".",
+				},
+				cli.StringFlag{
+					Name:  "stats-index",
+					Usage: "The index used to compute statistics of ranges of digits, see \"piio index stats\". Without one all digits of a range are read.",
+				},
+				cli.Int64Flag{
+					Name:  "max-stats-size",
+					Usage: "The maximum amount of digits counted per request without --stats-index.",
+					Value: rest.DefaultMaximumStatsSize,
+				},
+				cli.BoolFlag{
+					Name:  "skip-checksum",
+					Usage: "Only validate the header of the file of pi without verifying its checksum.",
+				},
 			},
 			Action: serveAction,
 		},
@@ -72,10 +166,7 @@ func compressAction(c *cli.Context) error {
 		return cli.NewExitError("expected exactly two arguments usage: piio compress <infile> <outfile>", 1)
 	}
 
-	chunkSize := defaultChunkSize
-
-	var in readSeekCloser
-	var out io.WriteCloser
+	var in io.ReadCloser
 	var err error
 
 	if infile == "-" {
@@ -87,28 +178,23 @@ func compressAction(c *cli.Context) error {
 		}
 	}
 	defer in.Close()
-	if outfile == "-" {
-		out = os.Stdout
-	} else {
-		out, err = os.Create(outfile)
-		if err != nil {
-			return cli.NewExitError(err, 2)
-		}
+	out, err := createOutput(outfile)
+	if err != nil {
+		return cli.NewExitError(err, 2)
 	}
 	defer out.Close()
 
-	var chnk piio.Chunk
-	for i := int64(0); err == nil; i += int64(chunkSize) {
-		chnk, err = piio.ReadTextChunk(in, i, chunkSize)
-		if err != nil {
-			break
-		}
-		err = piio.WriteChunk(chnk, piio.FileFormatCompressed, out)
-		if err != nil {
-			break
-		}
+	cw, err := newCompressWriter(out, piio.FileFormatCompressed, c.Bool("raw"))
+	if err != nil {
+		return cli.NewExitError(err, 2)
 	}
-	if err != io.EOF {
+
+	_, err = io.Copy(cw, in)
+	if err != nil {
+		return cli.NewExitError(err, 3)
+	}
+	err = cw.Close()
+	if err != nil {
 		return cli.NewExitError(err, 3)
 	}
 
@@ -116,18 +202,152 @@ func compressAction(c *cli.Context) error {
 }
 
 func serveAction(c *cli.Context) error {
-	chunkSource := piio.NewUncachedChunkSource(c.String("pi"), piio.FileFormatCompressed, c.Int("max-chunk-size"))
-	api := rest.NewAPI(chunkSource)
+	format, err := fileFormat(c.String("format"), c.String("pi"))
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	if format != piio.FileFormatYCD {
+		verify := piio.VerifyFile
+		if c.Bool("skip-checksum") {
+			verify = piio.ValidateFile
+		}
+		err = verify(c.String("pi"), format)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+
+	opts := []piio.Option{
+		piio.WithFormat(format),
+		piio.WithMaximumChunkSize(c.Int("max-chunk-size")),
+		piio.WithCache(int64(c.Int("cache-size")) << 20),
+	}
+	// Streams read large blocks past the cache.
+	streamOpts := []piio.Option{
+		piio.WithFormat(format),
+		piio.WithMaximumChunkSize(rest.StreamBlockSize),
+	}
+	if c.String("mmap") != "off" {
+		advice, err := piio.ParseAdvice(c.String("mmap"))
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		opts = append(opts, piio.WithMmap(advice), piio.WithMlock(c.Int64("mlock")))
+		streamOpts = append(streamOpts, piio.WithMmap(advice))
+	}
+	if c.Int64("compute-limit") > 0 {
+		opts = append(opts, piio.WithComputedDigits(c.Int64("compute-limit"), c.Int("compute-max-size"), c.Duration("compute-timeout")))
+	}
+
+	chunkSource, err := piio.Open(c.String("pi"), opts...)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	defer piio.Close(chunkSource)
+	streamSource, err := piio.Open(c.String("pi"), streamOpts...)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	defer piio.Close(streamSource)
+	apiOpts := []rest.Option{
+		rest.WithMaximumHexSize(c.Int("max-hex-size")),
+		rest.WithStreamSource(streamSource),
+		rest.WithMaximumStreamLength(c.Int64("max-stream-length")),
+		rest.WithMaximumBatchSize(c.Int64("max-batch-size")),
+		rest.WithMaximumStatsSize(c.Int64("max-stats-size")),
+	}
+	raw, err := rest.OpenRawFile(c.String("pi"), format)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	defer raw.Close()
+	apiOpts = append(apiOpts, rest.WithRawFile(raw))
+	stored := chunkSource
+	if fs, ok := chunkSource.(*piio.FallbackChunkSource); ok {
+		stored = fs.Stored()
+	}
+	if c.String("index") != "" {
+		index, err := search.OpenIndex(c.String("index"), stored)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		defer index.Close()
+		apiOpts = append(apiOpts, rest.WithSearcher(index))
+	}
+	if c.String("first-occurrence") != "" {
+		table, err := search.OpenFirstOccurrenceTable(c.String("first-occurrence"))
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		defer table.Close()
+		avail, err := stored.AvailableDigits()
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		if table.DigitCount() != avail {
+			return cli.NewExitError(fmt.Sprintf("the first occurrence table was built from %d digits, but the file of pi has %d", table.DigitCount(), avail), 2)
+		}
+		apiOpts = append(apiOpts, rest.WithFirstOccurrenceTable(table))
+	}
+	if c.String("stats-index") != "" {
+		// The statistics index is held in memory and needs
+		// no closing.
+		index, err := stats.OpenIndex(c.String("stats-index"), stored)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		apiOpts = append(apiOpts, rest.WithCounter(index))
+	}
+	api := rest.NewAPI(chunkSource, apiOpts...)
 
+	timeout := c.Duration("timeout")
+	streamTimeout := c.Duration("stream-timeout")
+	if streamTimeout < timeout {
+		streamTimeout = timeout
+	}
 	server := &http.Server{
 		Addr:           c.String("addr"),
 		MaxHeaderBytes: 512,
-		ReadTimeout:    5 * time.Second,
-		WriteTimeout:   5 * time.Second,
-		Handler:        api.Handler(),
+		ReadTimeout:    timeout,
+		// Leave time to write the error once a request times out.
+		WriteTimeout: timeout + time.Second,
+		Handler:      withTimeout(api.Handler(), timeout, streamTimeout),
 	}
 
-	err := server.ListenAndServe()
+	err = server.ListenAndServe()
 
 	return err
 }
+
+// withTimeout cancels the context of every request after
+// the given timeout, or streamTimeout for streams and the raw
+// file, so reads stop once the response could no longer be
+// written anyway. The write deadline of streams and the raw
+// file is extended accordingly.
+func withTimeout(h http.Handler, timeout, streamTimeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t := timeout
+		if strings.HasPrefix(r.URL.Path, rest.BaseURI+"v1/stream/") || r.URL.Path == rest.BaseURI+"v1/raw" {
+			t = streamTimeout
+			err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(t + time.Second))
+			if err != nil {
+				// Keep the write deadline of the server rather
+				// than sending a truncated response.
+				t = timeout
+			}
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), t)
+		defer cancel()
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// fileFormat parses the given format name, detecting the
+// format of the file if the name is "auto".
+func fileFormat(name, filename string) (piio.FileFormat, error) {
+	if name == "auto" {
+		return piio.DetectFormat(filename)
+	}
+	return piio.ParseFileFormat(name)
+}
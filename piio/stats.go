@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/targodan/piio/rest"
+	"github.com/targodan/piio/stats"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// statsBlockSize is the amount of digits counted at once by
+// the stats command.
+const statsBlockSize = 1 << 20
+
+var statsCommand = cli.Command{
+	Name:      "stats",
+	Usage:     "prints the frequencies of the digits of a range of pi",
+	ArgsUsage: "<start> <length>",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "pi,p",
+			Usage: "The file of pi.",
+			Value: "pi.bin",
+		},
+		cli.StringFlag{
+			Name:  "format,f",
+			Usage: "The format of the file of pi. One of auto, compressed, text, dense or ycd.",
+			Value: "auto",
+		},
+		cli.StringFlag{
+			Name:  "index",
+			Usage: "The statistics index of the file, see \"piio index stats\". Without one all digits of the range are read.",
+		},
+		cli.StringFlag{
+			Name:  "output,o",
+			Usage: "The output format. One of text or json.",
+			Value: "text",
+		},
+	},
+	Action: statsAction,
+}
+
+func statsAction(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return cli.NewExitError("expected exactly two arguments usage: piio stats <start> <length>", 1)
+	}
+	start, err := strconv.ParseInt(c.Args().Get(0), 10, 64)
+	if err != nil {
+		return cli.NewExitError("the start must be a number, got "+c.Args().Get(0), 1)
+	}
+	length, err := strconv.ParseInt(c.Args().Get(1), 10, 64)
+	if err != nil {
+		return cli.NewExitError("the length must be a number, got "+c.Args().Get(1), 1)
+	}
+	if c.String("output") != "text" && c.String("output") != "json" {
+		return cli.NewExitError(fmt.Sprintf("unknown output format \"%s\"", c.String("output")), 1)
+	}
+
+	cs, err := openIndexSource(c.String("pi"), c.String("format"), statsBlockSize)
+	if err != nil {
+		return err
+	}
+	var counter stats.Counter = stats.NewScanner(cs)
+	if c.String("index") != "" {
+		counter, err = stats.OpenIndex(c.String("index"), cs)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+
+	s, err := stats.Compute(context.Background(), counter, start, length)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+
+	if c.String("output") == "json" {
+		err = json.NewEncoder(os.Stdout).Encode(&rest.StatsResponse{
+			FirstIndex: s.Range.FirstIndex,
+			Size:       s.Range.Size,
+			Counts:     s.Counts,
+			Mean:       s.Mean,
+			ChiSquare:  s.ChiSquare,
+		})
+		if err != nil {
+			return cli.NewExitError(err, 3)
+		}
+		return nil
+	}
+
+	fmt.Printf("range       %s\n", s.Range)
+	for d, n := range s.Counts {
+		fmt.Printf("digit %d     %d\n", d, n)
+	}
+	fmt.Printf("total       %d\n", s.Counts.Total())
+	fmt.Printf("mean        %f\n", s.Mean)
+	fmt.Printf("chi-square  %f (9 degrees of freedom)\n", s.ChiSquare)
+	return nil
+}
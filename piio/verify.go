@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/targodan/piio"
+	"github.com/targodan/piio/chudnovsky"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var verifyCommand = cli.Command{
+	Name:      "verify",
+	Usage:     "checks a file of digits of pi for corruption",
+	ArgsUsage: "<file>",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "format,f",
+			Usage: "The format of the file. One of auto, compressed, text, dense or ycd.",
+			Value: "auto",
+		},
+		cli.IntFlag{
+			Name:  "prefix",
+			Usage: "Compare the given amount of digits at the start of the file with computed ones. The digits after it are only checked for invalid values and against the checksum.",
+			Value: 100000,
+		},
+		cli.BoolFlag{
+			Name:  "quiet,q",
+			Usage: "Do not display the progress on stderr.",
+		},
+	},
+	Action: verifyAction,
+}
+
+func verifyAction(c *cli.Context) error {
+	filename := c.Args().Get(0)
+	if filename == "" || c.NArg() != 1 {
+		return cli.NewExitError("expected exactly one argument usage: piio verify <file>", 1)
+	}
+	format, err := fileFormat(c.String("format"), filename)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	// The cheap checks of the header and size come first,
+	// the checksum last as it cannot locate any error.
+	if format != piio.FileFormatYCD {
+		err = piio.ValidateFile(filename, format)
+		if err != nil {
+			return verifyFailed(err)
+		}
+	}
+
+	digits, err := scanDigits(filename, format, c.Int("prefix"), !c.Bool("quiet") && isTerminal(os.Stderr))
+	if err != nil {
+		return verifyFailed(err)
+	}
+
+	if format != piio.FileFormatYCD {
+		err = piio.VerifyFile(filename, format)
+		if err != nil {
+			return verifyFailed(err)
+		}
+	}
+
+	fmt.Printf("ok, %d digits verified\n", digits)
+	return nil
+}
+
+// verifyFailed reports the failed verification and
+// returns the matching exit error.
+func verifyFailed(err error) error {
+	var dataErr *piio.CorruptDataError
+	if errors.As(err, &dataErr) && dataErr.Index >= 0 {
+		return cli.NewExitError(fmt.Sprintf("verification failed, first bad digit at index %d: %s", dataErr.Index, dataErr.Reason), 5)
+	}
+	if errors.Is(err, piio.ErrCorruptData) {
+		return cli.NewExitError("verification failed: "+err.Error(), 5)
+	}
+	return cli.NewExitError(err, 2)
+}
+
+// scanDigits reads all digits of the file, which detects
+// invalid digits, and compares the first ones with
+// computed digits of pi. It returns the amount of digits.
+func scanDigits(filename string, format piio.FileFormat, prefix int, showProgress bool) (int64, error) {
+	in, total, err := openDigits(filename, format.String())
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	var r io.Reader = in
+	if showProgress {
+		p := &progress{
+			action: "verified",
+			total:  total,
+			w:      os.Stderr,
+		}
+		defer p.Done()
+		r = io.TeeReader(r, p)
+	}
+
+	if total >= 0 && int64(prefix) > total {
+		prefix = int(total)
+	}
+	if prefix > 0 {
+		expected, err := chudnovsky.Pi(context.Background(), prefix, chudnovsky.Config{})
+		if err != nil {
+			return 0, err
+		}
+		actual := make([]byte, prefix)
+		n, err := io.ReadFull(r, actual)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return 0, err
+		}
+		if i := mismatch(actual[:n], expected[:n]); i >= 0 {
+			return 0, &piio.CorruptDataError{
+				Index:  int64(i),
+				Reason: fmt.Sprintf("expected digit %c, got %c", expected[i], actual[i]),
+			}
+		}
+		if n < prefix {
+			return int64(n), nil
+		}
+	}
+
+	n, err := io.Copy(ioutil.Discard, r)
+	return int64(prefix) + n, err
+}
+
+// mismatch returns the index of the first difference of
+// a and b or -1 if they are equal.
+func mismatch(a, b []byte) int {
+	if bytes.Equal(a, b) {
+		return -1
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return len(a)
+}
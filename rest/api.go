@@ -1,48 +1,231 @@
 package rest
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/targodan/piio"
+	"github.com/targodan/piio/search"
+	"github.com/targodan/piio/stats"
 
 	"github.com/julienschmidt/httprouter"
-	errors "github.com/targodan/go-errors"
 )
 
 const BaseURI = "/api/"
 
+// DefaultMaximumHexSize is the default maximum amount of
+// hexadecimal digits computed per request.
+const DefaultMaximumHexSize = 64
+
+// DefaultMaximumStreamLength is the default maximum amount of
+// digits written per request to the stream endpoint.
+const DefaultMaximumStreamLength = 100000000
+
+// DefaultMaximumStatsSize is the default maximum amount of
+// digits counted per request if no Counter is set.
+const DefaultMaximumStatsSize = 100000000
+
+// streamFlushSize is the amount of bytes buffered before
+// they are sent to the client by the stream endpoint.
+const streamFlushSize = 1 << 16
+
+// StreamBlockSize is the amount of digits read at once by
+// the stream endpoint. See WithStreamSource.
+const StreamBlockSize = 1 << 16
+
+// DefaultSearchLimit is the amount of positions returned
+// per page of search results unless requested otherwise.
+const DefaultSearchLimit = 100
+
+// MaximumSearchLimit is the maximum amount of positions
+// returned per page of search results.
+const MaximumSearchLimit = 1000
+
 type API struct {
-	router      *httprouter.Router
-	chunkSource piio.ChunkSource
+	router          *httprouter.Router
+	chunkSource     piio.ChunkSourceContext
+	searcher        search.Searcher
+	firstTable      *search.FirstOccurrenceTable
+	counter         stats.Counter
+	streamSource    piio.ChunkSourceContext
+	rawFile         *RawFile
+	maxHexSize      int
+	maxStreamLength int64
+	maxBatchSize    int64
+	// maxStatsSize limits the statistics requests if the
+	// digits are counted by reading them.
+	maxStatsSize int64
+}
+
+// Option configures an API.
+type Option func(*API)
+
+// WithMaximumHexSize sets the maximum amount of hexadecimal
+// digits computed per request.
+func WithMaximumHexSize(size int) Option {
+	return func(api *API) {
+		api.maxHexSize = size
+	}
+}
+
+// WithSearcher sets the Searcher used to find sequences of
+// digits. By default the stored digits are scanned.
+func WithSearcher(searcher search.Searcher) Option {
+	return func(api *API) {
+		api.searcher = searcher
+	}
+}
+
+// WithFirstOccurrenceTable answers the lookups of the first
+// occurrence of sequences of the length of the table using it
+// instead of the Searcher.
+func WithFirstOccurrenceTable(table *search.FirstOccurrenceTable) Option {
+	return func(api *API) {
+		api.firstTable = table
+	}
+}
+
+// WithCounter sets the Counter used to compute the
+// statistics of ranges of digits. By default the stored
+// digits are counted.
+func WithCounter(counter stats.Counter) Option {
+	return func(api *API) {
+		api.counter = counter
+	}
+}
+
+// WithMaximumStatsSize sets the maximum amount of digits
+// counted per request. It does not apply to a Counter set with
+// WithCounter, which is expected to use an index.
+func WithMaximumStatsSize(size int64) Option {
+	return func(api *API) {
+		api.maxStatsSize = size
+	}
+}
+
+// WithMaximumStreamLength sets the maximum amount of digits
+// written per request to the stream endpoint.
+func WithMaximumStreamLength(length int64) Option {
+	return func(api *API) {
+		api.maxStreamLength = length
+	}
+}
+
+// WithStreamSource sets the source the stream endpoint reads
+// the digits from, which should support chunks of
+// StreamBlockSize digits. By default the stored digits of the
+// source of the API are read in chunks of its maximum size.
+func WithStreamSource(source piio.ChunkSource) Option {
+	return func(api *API) {
+		api.streamSource = piio.WithContext(source)
+	}
+}
+
+// WithMaximumBatchSize sets the maximum amount of digits
+// requested per batch.
+func WithMaximumBatchSize(size int64) Option {
+	return func(api *API) {
+		api.maxBatchSize = size
+	}
+}
+
+// WithRawFile serves the file of digits as it is on the raw
+// endpoint if it holds packed digits, see OpenRawFile. Its ETag is also used to derive the ETags of all
+// other responses containing digits.
+func WithRawFile(f *RawFile) Option {
+	return func(api *API) {
+		api.rawFile = f
+	}
 }
 
 func writeJson(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", mediaTypeJSON)
+	jw := json.NewEncoder(w)
+	jw.Encode(data)
+}
+
+// writeJsonStatus writes the data as JSON with the given
+// status code. Errors are never cached.
+func writeJsonStatus(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", mediaTypeJSON)
+	if status >= 400 {
+		w.Header().Del("Cache-Control")
+		w.Header().Del("ETag")
+	}
+	w.WriteHeader(status)
 	jw := json.NewEncoder(w)
 	jw.Encode(data)
 }
 
-func NewAPI(chunkSource piio.ChunkSource) *API {
+// errorStatus returns the HTTP status code matching
+// the given error.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, piio.ErrOutOfRange):
+		return http.StatusRequestedRangeNotSatisfiable
+	case errors.Is(err, piio.ErrChunkTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, piio.ErrInvalidArgument):
+		return http.StatusBadRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+func NewAPI(chunkSource piio.ChunkSource, opts ...Option) *API {
 	router := httprouter.New()
 	api := &API{
-		router:      router,
-		chunkSource: chunkSource,
+		router:          router,
+		chunkSource:     piio.WithContext(chunkSource),
+		maxHexSize:      DefaultMaximumHexSize,
+		maxStreamLength: DefaultMaximumStreamLength,
+		maxBatchSize:    DefaultMaximumBatchSize,
+		maxStatsSize:    DefaultMaximumStatsSize,
+	}
+	for _, opt := range opts {
+		opt(api)
+	}
+	stored := chunkSource
+	if fs, ok := chunkSource.(*piio.FallbackChunkSource); ok {
+		stored = fs.Stored()
+	}
+	if api.streamSource == nil {
+		api.streamSource = piio.WithContext(stored)
+	}
+	if api.searcher == nil {
+		api.searcher = search.NewScanner(stored)
+	}
+	if api.counter == nil {
+		api.counter = stats.NewScanner(stored)
+	} else {
+		api.maxStatsSize = 0
 	}
 
 	router.GET(BaseURI+"v1/digit/:index", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 		index, err := strconv.ParseInt(p.ByName("index"), 10, 64)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
 			errMsg := "the index must be a number, got " + p.ByName("index")
-			writeJson(w, &DigitResponse{Error: &errMsg})
+			writeJsonStatus(w, http.StatusBadRequest, &DigitResponse{Error: &errMsg})
+			return
+		}
+		if api.notModified(w, r, "") {
 			return
 		}
-		d, err := api.GetDigit(index)
+		d, err := api.GetDigitContext(r.Context(), index)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
 			errMsg := err.Error()
-			writeJson(w, &DigitResponse{Error: &errMsg})
+			writeJsonStatus(w, errorStatus(err), &DigitResponse{Error: &errMsg})
 			return
 		}
 		writeJson(w, &DigitResponse{
@@ -52,94 +235,476 @@ func NewAPI(chunkSource piio.ChunkSource) *API {
 	})
 
 	router.GET(BaseURI+"v1/chunk/:startIndex/:size", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		w.Header().Set("Vary", "Accept")
+		mediaType := negotiate(r.Header.Get("Accept"), chunkMediaTypes)
+		if mediaType == "" {
+			errMsg := "none of the accepted media types is supported, use one of " + strings.Join(chunkMediaTypes, ", ")
+			writeJsonStatus(w, http.StatusNotAcceptable, &ChunkResponse{Error: &errMsg})
+			return
+		}
+
 		index, err := strconv.ParseInt(p.ByName("startIndex"), 10, 64)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
 			errMsg := "the start index must be a number, got " + p.ByName("startIndex")
-			writeJson(w, &ChunkResponse{Error: &errMsg})
+			writeJsonStatus(w, http.StatusBadRequest, &ChunkResponse{Error: &errMsg})
 			return
 		}
 		size, err := strconv.ParseInt(p.ByName("size"), 10, 32)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
 			errMsg := "the size must be a number, got " + p.ByName("size")
-			writeJson(w, &ChunkResponse{Error: &errMsg})
+			writeJsonStatus(w, http.StatusBadRequest, &ChunkResponse{Error: &errMsg})
 			return
 		}
-		getSize := size
-		if getSize%2 != 0 {
-			getSize++
+		if api.notModified(w, r, mediaType) {
+			return
+		}
+		chnk, err := api.GetChunkContext(r.Context(), index, int(size))
+		if err != nil {
+			errMsg := err.Error()
+			writeJsonStatus(w, errorStatus(err), &ChunkResponse{Error: &errMsg})
+			return
 		}
-		firstIndex := index
-		if firstIndex%2 != 0 {
-			firstIndex--
-			getSize += 2
+
+		writeChunk(w, mediaType, chnk)
+	})
+	router.GET(BaseURI+"v1/stream/:start/:length", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		w.Header().Set("Vary", "Accept")
+		mediaType := negotiate(r.Header.Get("Accept"), streamMediaTypes)
+		if mediaType == "" {
+			errMsg := "none of the accepted media types is supported, use one of " + strings.Join(streamMediaTypes, ", ")
+			writeJsonStatus(w, http.StatusNotAcceptable, &ChunkResponse{Error: &errMsg})
+			return
 		}
 
-		chnk, err := api.GetChunk(firstIndex, int(getSize))
+		start, err := strconv.ParseInt(p.ByName("start"), 10, 64)
+		if err != nil {
+			errMsg := "the start must be a number, got " + p.ByName("start")
+			writeJsonStatus(w, http.StatusBadRequest, &ChunkResponse{Error: &errMsg})
+			return
+		}
+		length, err := strconv.ParseInt(p.ByName("length"), 10, 64)
+		if err != nil {
+			errMsg := "the length must be a number, got " + p.ByName("length")
+			writeJsonStatus(w, http.StatusBadRequest, &ChunkResponse{Error: &errMsg})
+			return
+		}
+		// Errors can only be reported before the first digit
+		// is written.
+		err = api.checkStream(start, length)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
 			errMsg := err.Error()
-			writeJson(w, &ChunkResponse{Error: &errMsg})
+			writeJsonStatus(w, errorStatus(err), &ChunkResponse{Error: &errMsg})
+			return
+		}
+		if api.notModified(w, r, mediaType) {
 			return
 		}
 
-		unChnk := piio.AsUncompressedChunk(chnk)
+		format := piio.FileFormatText
+		if mediaType == mediaTypeBinary {
+			format = piio.FileFormatCompressed
+			w.Header().Set("Content-Type", mediaTypeBinary)
+			w.Header().Set("X-First-Index", strconv.FormatInt(start, 10))
+			w.Header().Set("X-Digit-Count", strconv.FormatInt(length, 10))
+		} else {
+			w.Header().Set("Content-Type", mediaTypeText+"; charset=utf-8")
+		}
 
-		if firstIndex != index && len(unChnk.Digits) > 1 {
-			// We requested one too early
-			unChnk.Digits = unChnk.Digits[1:]
-			unChnk.FirstDigitIndex++
+		bw := bufio.NewWriterSize(flushingWriter{w}, streamFlushSize)
+		err = api.StreamContext(r.Context(), bw, start, length, format)
+		if err == nil {
+			err = bw.Flush()
 		}
-		if size != getSize && len(unChnk.Digits) > int(size) {
-			unChnk.Digits = unChnk.Digits[:size]
+		if err != nil {
+			// Closing the connection lets the client know
+			// the response is incomplete.
+			panic(http.ErrAbortHandler)
+		}
+	})
+	router.GET(BaseURI+"v1/hex/:index/:size", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		index, err := strconv.ParseInt(p.ByName("index"), 10, 64)
+		if err != nil {
+			errMsg := "the index must be a number, got " + p.ByName("index")
+			writeJsonStatus(w, http.StatusBadRequest, &HexResponse{Error: &errMsg})
+			return
+		}
+		size, err := strconv.ParseInt(p.ByName("size"), 10, 32)
+		if err != nil {
+			errMsg := "the size must be a number, got " + p.ByName("size")
+			writeJsonStatus(w, http.StatusBadRequest, &HexResponse{Error: &errMsg})
+			return
+		}
+		if api.notModified(w, r, "") {
+			return
+		}
+		digits, err := api.GetHexDigitsContext(r.Context(), index, int(size))
+		if err != nil {
+			errMsg := err.Error()
+			writeJsonStatus(w, errorStatus(err), &HexResponse{Error: &errMsg})
+			return
 		}
 
-		digits := make([]int, len(unChnk.Digits))
-		for i, d := range unChnk.Digits {
-			digits[i] = int(d)
+		hex := make([]byte, len(digits))
+		for i, d := range digits {
+			hex[i] = "0123456789abcdef"[d]
 		}
-		writeJson(w, &ChunkResponse{
-			FirstIndex: unChnk.FirstIndex(),
-			Digits:     digits,
+		writeJson(w, &HexResponse{
+			FirstIndex: index,
+			Digits:     string(hex),
+		})
+	})
+	if api.rawFile != nil {
+		raw := func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			api.rawFile.serve(w, r)
+		}
+		router.GET(BaseURI+"v1/raw", raw)
+		router.HEAD(BaseURI+"v1/raw", raw)
+	}
+	router.GET(BaseURI+"v1/search/:sequence", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		offset, limit := 0, DefaultSearchLimit
+		var err error
+		if v := r.URL.Query().Get("offset"); v != "" {
+			offset, err = strconv.Atoi(v)
+			if err != nil {
+				errMsg := "the offset must be a number, got " + v
+				writeJsonStatus(w, http.StatusBadRequest, &SearchResponse{Error: &errMsg})
+				return
+			}
+		}
+		if v := r.URL.Query().Get("limit"); v != "" {
+			limit, err = strconv.Atoi(v)
+			if err != nil {
+				errMsg := "the limit must be a number, got " + v
+				writeJsonStatus(w, http.StatusBadRequest, &SearchResponse{Error: &errMsg})
+				return
+			}
+		}
+		result, err := api.SearchContext(r.Context(), p.ByName("sequence"), offset, limit)
+		if err != nil {
+			errMsg := err.Error()
+			writeJsonStatus(w, errorStatus(err), &SearchResponse{Error: &errMsg})
+			return
+		}
+		writeJson(w, &SearchResponse{
+			Sequence:        p.ByName("sequence"),
+			FirstOccurrence: result.FirstOccurrence,
+			Count:           result.Count,
+			Offset:          offset,
+			Limit:           limit,
+			Positions:       result.Positions,
+		})
+	})
+	router.GET(BaseURI+"v1/first/:sequence", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		pos, err := api.FirstOccurrenceContext(r.Context(), p.ByName("sequence"))
+		if err != nil {
+			errMsg := err.Error()
+			writeJsonStatus(w, errorStatus(err), &FirstOccurrenceResponse{Error: &errMsg})
+			return
+		}
+		writeJson(w, &FirstOccurrenceResponse{
+			Sequence:        p.ByName("sequence"),
+			FirstOccurrence: pos,
 		})
 	})
+	router.GET(BaseURI+"v1/stats/:start/:size", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		start, err := strconv.ParseInt(p.ByName("start"), 10, 64)
+		if err != nil {
+			errMsg := "the start must be a number, got " + p.ByName("start")
+			writeJsonStatus(w, http.StatusBadRequest, &StatsResponse{Error: &errMsg})
+			return
+		}
+		size, err := strconv.ParseInt(p.ByName("size"), 10, 64)
+		if err != nil {
+			errMsg := "the size must be a number, got " + p.ByName("size")
+			writeJsonStatus(w, http.StatusBadRequest, &StatsResponse{Error: &errMsg})
+			return
+		}
+		s, err := api.StatsContext(r.Context(), start, size)
+		if err != nil {
+			errMsg := err.Error()
+			writeJsonStatus(w, errorStatus(err), &StatsResponse{Error: &errMsg})
+			return
+		}
+		writeJson(w, &StatsResponse{
+			FirstIndex: s.Range.FirstIndex,
+			Size:       s.Range.Size,
+			Counts:     s.Counts,
+			Mean:       s.Mean,
+			ChiSquare:  s.ChiSquare,
+		})
+	})
+	router.POST(BaseURI+"v2/batch", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBatchBodySize+1))
+		if err != nil {
+			errMsg := "could not read the request: " + err.Error()
+			writeJsonStatus(w, http.StatusBadRequest, &BatchResponse{Error: &errMsg})
+			return
+		}
+		if len(body) > maxBatchBodySize {
+			errMsg := fmt.Sprintf("the request must not be larger than %d bytes", maxBatchBodySize)
+			writeJsonStatus(w, http.StatusRequestEntityTooLarge, &BatchResponse{Error: &errMsg})
+			return
+		}
+		var req BatchRequest
+		err = json.Unmarshal(body, &req)
+		if err != nil {
+			errMsg := "invalid request: " + err.Error()
+			writeJsonStatus(w, http.StatusBadRequest, &BatchResponse{Error: &errMsg})
+			return
+		}
+		results, err := api.BatchContext(r.Context(), req.Items)
+		if err != nil {
+			errMsg := err.Error()
+			writeJsonStatus(w, errorStatus(err), &BatchResponse{Error: &errMsg})
+			return
+		}
+		writeJson(w, &BatchResponse{Results: results})
+	})
 	router.GET(BaseURI+"v1/settings", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-		avail, err := chunkSource.AvailableDigits()
+		settings, err := api.Settings()
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
 			errMsg := err.Error()
-			writeJson(w, &SettingsResponse{
+			writeJsonStatus(w, errorStatus(err), &SettingsResponse{
 				Error: &errMsg,
 			})
+			return
 		}
-		writeJson(w, &SettingsResponse{
-			AvailableDigits:  avail,
-			MaximumChunkSize: chunkSource.MaximumChunkSize(),
-		})
+		writeJson(w, settings)
 	})
 
 	return api
 }
 
-func (api *API) GetDigit(index int64) (byte, error) {
-	firstIndex := index
-	if firstIndex%2 != 0 {
-		firstIndex--
+// flushingWriter sends every write to the client
+// immediately.
+type flushingWriter struct {
+	w http.ResponseWriter
+}
+
+func (fw flushingWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if f, ok := fw.w.(http.Flusher); ok {
+		f.Flush()
 	}
-	chnk, err := api.chunkSource.GetChunk(firstIndex, 2)
+	return n, err
+}
+
+// writeChunk writes the chunk as the given media type. Errors
+// are always written as a JSON ChunkResponse.
+func writeChunk(w http.ResponseWriter, mediaType string, chnk piio.Chunk) {
+	switch mediaType {
+	case mediaTypeText:
+		w.Header().Set("Content-Type", mediaTypeText+"; charset=utf-8")
+		piio.WriteChunk(chnk, piio.FileFormatText, w)
+		return
+
+	case mediaTypeBinary:
+		// The amount of digits is needed to tell whether the
+		// low nibble of the last byte is padding.
+		w.Header().Set("Content-Type", mediaTypeBinary)
+		w.Header().Set("X-First-Index", strconv.FormatInt(chnk.FirstIndex(), 10))
+		w.Header().Set("X-Digit-Count", strconv.Itoa(chnk.Length()))
+		piio.WriteChunk(chnk, piio.FileFormatCompressed, w)
+		return
+	}
+
+	unChnk := piio.AsUncompressedChunk(chnk)
+	switch mediaType {
+	case mediaTypeCBOR:
+		w.Header().Set("Content-Type", mediaTypeCBOR)
+		w.Write(encodeCBORChunk(unChnk.FirstIndex(), unChnk.Digits))
+
+	case mediaTypeCompactJSON:
+		digits := make([]byte, len(unChnk.Digits))
+		for i, d := range unChnk.Digits {
+			digits[i] = '0' + d
+		}
+		w.Header().Set("Content-Type", mediaTypeCompactJSON)
+		json.NewEncoder(w).Encode(&CompactChunkResponse{
+			FirstIndex: unChnk.FirstIndex(),
+			Digits:     string(digits),
+		})
+
+	default:
+		digits := make([]int, len(unChnk.Digits))
+		for i, d := range unChnk.Digits {
+			digits[i] = int(d)
+		}
+		writeJson(w, &ChunkResponse{
+			FirstIndex: unChnk.FirstIndex(),
+			Digits:     digits,
+		})
+	}
+}
+
+func (api *API) GetDigit(index int64) (byte, error) {
+	return api.GetDigitContext(context.Background(), index)
+}
+
+func (api *API) GetDigitContext(ctx context.Context, index int64) (byte, error) {
+	chnk, err := api.chunkSource.GetChunkContext(ctx, index, 1)
 	if err != nil {
-		return 255, errors.Wrap("could not load digit", err)
+		return 255, fmt.Errorf("could not load digit: %w", err)
 	}
 	d, err := chnk.Digit(index)
 	if err != nil {
-		return 255, errors.Wrap("could not load digit", err)
+		return 255, fmt.Errorf("could not load digit: %w", err)
 	}
 	return d, nil
 }
 
 func (api *API) GetChunk(firstIndex int64, size int) (piio.Chunk, error) {
-	return api.chunkSource.GetChunk(firstIndex, size)
+	return api.GetChunkContext(context.Background(), firstIndex, size)
+}
+
+func (api *API) GetChunkContext(ctx context.Context, firstIndex int64, size int) (piio.Chunk, error) {
+	return api.chunkSource.GetChunkContext(ctx, firstIndex, size)
+}
+
+func (api *API) Stream(w io.Writer, firstIndex, length int64, format piio.FileFormat) error {
+	return api.StreamContext(context.Background(), w, firstIndex, length, format)
+}
+
+// StreamContext writes the stored digits of the range to w in
+// FileFormatText or FileFormatCompressed, reading them in
+// blocks of StreamBlockSize digits or the maximum chunk size
+// of the stream source if it is smaller.
+func (api *API) StreamContext(ctx context.Context, w io.Writer, firstIndex, length int64, format piio.FileFormat) error {
+	err := api.checkStream(firstIndex, length)
+	if err != nil {
+		return err
+	}
+
+	// All compressed blocks but the last have to contain an
+	// even amount of digits.
+	blockSize := int64(StreamBlockSize)
+	if max := int64(api.streamSource.MaximumChunkSize()); max < blockSize {
+		blockSize = max
+	}
+	blockSize &^= 1
+	for pos, end := firstIndex, firstIndex+length; pos < end; {
+		n := end - pos
+		if n > blockSize {
+			n = blockSize
+		}
+		chnk, err := api.streamSource.GetChunkContext(ctx, pos, int(n))
+		if err != nil {
+			return err
+		}
+		if int64(chnk.Length()) != n {
+			return &piio.CorruptDataError{Index: pos + int64(chnk.Length()), Reason: "fewer digits than available"}
+		}
+		err = piio.WriteChunk(chnk, format, w)
+		if err != nil {
+			return err
+		}
+		pos += n
+	}
+	return nil
+}
+
+// checkStream checks whether the range can be streamed.
+func (api *API) checkStream(firstIndex, length int64) error {
+	if firstIndex < 0 || length <= 0 {
+		return &piio.InvalidArgumentError{Reason: "the start must not be negative and the length must be positive"}
+	}
+	if length > api.maxStreamLength {
+		return &piio.ChunkTooLargeError{
+			Size:        int(length),
+			MaximumSize: int(api.maxStreamLength),
+		}
+	}
+	avail, err := api.streamSource.AvailableDigits()
+	if err != nil {
+		return err
+	}
+	if firstIndex+length > avail {
+		return &piio.OutOfRangeError{
+			Requested: piio.Range{FirstIndex: firstIndex, Size: length},
+			Available: piio.Range{FirstIndex: 0, Size: avail},
+		}
+	}
+	return nil
+}
+
+func (api *API) GetHexDigits(firstIndex int64, size int) ([]byte, error) {
+	return api.GetHexDigitsContext(context.Background(), firstIndex, size)
+}
+
+func (api *API) GetHexDigitsContext(ctx context.Context, firstIndex int64, size int) ([]byte, error) {
+	if size > api.maxHexSize {
+		return nil, &piio.ChunkTooLargeError{
+			Size:        size,
+			MaximumSize: api.maxHexSize,
+		}
+	}
+	return piio.HexDigitsAtContext(ctx, firstIndex, size)
+}
+
+func (api *API) Search(sequence string, offset, limit int) (*search.Result, error) {
+	return api.SearchContext(context.Background(), sequence, offset, limit)
+}
+
+func (api *API) SearchContext(ctx context.Context, sequence string, offset, limit int) (*search.Result, error) {
+	if limit > MaximumSearchLimit {
+		return nil, &piio.InvalidArgumentError{Reason: fmt.Sprintf("the limit must not be larger than %d", MaximumSearchLimit)}
+	}
+	seq, err := search.ParseSequence(sequence)
+	if err != nil {
+		return nil, err
+	}
+	return api.searcher.Search(ctx, seq, offset, limit)
+}
+
+func (api *API) FirstOccurrence(sequence string) (int64, error) {
+	return api.FirstOccurrenceContext(context.Background(), sequence)
+}
+
+func (api *API) FirstOccurrenceContext(ctx context.Context, sequence string) (int64, error) {
+	seq, err := search.ParseSequence(sequence)
+	if err != nil {
+		return 0, err
+	}
+	if api.firstTable != nil && len(seq) == api.firstTable.Length() {
+		return api.firstTable.Lookup(seq)
+	}
+	return api.searcher.First(ctx, seq)
+}
+
+func (api *API) Stats(firstIndex int64, size int64) (*stats.Stats, error) {
+	return api.StatsContext(context.Background(), firstIndex, size)
+}
+
+func (api *API) StatsContext(ctx context.Context, firstIndex int64, size int64) (*stats.Stats, error) {
+	if api.maxStatsSize > 0 && size > api.maxStatsSize {
+		return nil, &piio.ChunkTooLargeError{
+			Size:        int(size),
+			MaximumSize: int(api.maxStatsSize),
+		}
+	}
+	return stats.Compute(ctx, api.counter, firstIndex, size)
+}
+
+func (api *API) Settings() (*SettingsResponse, error) {
+	avail, err := api.chunkSource.AvailableDigits()
+	if err != nil {
+		return nil, err
+	}
+	settings := &SettingsResponse{
+		AvailableDigits:  avail,
+		MaximumChunkSize: api.chunkSource.MaximumChunkSize(),
+		StoredDigits:     piio.Range{FirstIndex: 0, Size: avail},
+	}
+
+	if fs, ok := api.chunkSource.(*piio.FallbackChunkSource); ok {
+		ranges, err := fs.Ranges()
+		if err != nil {
+			return nil, err
+		}
+		settings.StoredDigits = ranges.Stored
+		settings.ComputedDigits = &ranges.Fallback
+	}
+	return settings, nil
 }
 
 func (api *API) Handler() http.Handler {
@@ -0,0 +1,310 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/targodan/piio"
+	"github.com/targodan/piio/chudnovsky"
+	"github.com/targodan/piio/internal/piiotest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// slowChunkSource never answers before the context is done.
+type slowChunkSource struct {
+	piiotest.MemoryChunkSource
+}
+
+func (cs *slowChunkSource) GetChunkContext(ctx context.Context, firstIndex int64, size int) (piio.Chunk, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// testDigits returns the first n digits of pi as text and
+// as digit values.
+func testDigits(t *testing.T, n int) (string, []byte) {
+	text, err := chudnovsky.Pi(context.Background(), n, chudnovsky.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	digits := make([]byte, len(text))
+	for i, c := range text {
+		digits[i] = c - '0'
+	}
+	return string(text), digits
+}
+
+// writeRawFile writes the digits as a compressed file with
+// a header and opens it as a RawFile.
+func writeRawFile(t *testing.T, digits []byte, format piio.FileFormat) *RawFile {
+	file, err := ioutil.TempFile("", "piio-rest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	fw, err := piio.NewFileWriter(file, piio.FileFormatCompressed)
+	if err == nil {
+		err = fw.WriteChunk(&piio.UncompressedChunk{Digits: digits})
+	}
+	if err == nil {
+		err = fw.Close()
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		t.Fatal(err)
+	}
+
+	raw, err := OpenRawFile(file.Name(), format)
+	os.Remove(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// do sends the request to the handler and returns the
+// recorded response.
+func do(h http.Handler, method, path string, body string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestAPI(t *testing.T) {
+	text, digits := testDigits(t, 1000)
+	raw := writeRawFile(t, digits, piio.FileFormatCompressed)
+	defer raw.Close()
+
+	api := NewAPI(
+		&piiotest.MemoryChunkSource{Digits: digits, MaxSize: 64},
+		WithRawFile(raw),
+		WithMaximumHexSize(16),
+		WithMaximumStreamLength(500),
+		WithMaximumBatchSize(100),
+		WithMaximumStatsSize(500),
+	)
+	h := api.Handler()
+
+	Convey("Given an API", t, func() {
+		Convey("the digit endpoint should", func() {
+			Convey("return a digit.", func() {
+				w := do(h, "GET", BaseURI+"v1/digit/2", "")
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Header().Get("Cache-Control"), ShouldEqual, cacheControl)
+				var resp DigitResponse
+				So(json.Unmarshal(w.Body.Bytes(), &resp), ShouldBeNil)
+				So(resp.Digit, ShouldEqual, 4)
+				So(resp.Error, ShouldBeNil)
+
+				w = do(h, "GET", BaseURI+"v1/digit/2", "", "If-None-Match", w.Header().Get("ETag"))
+				So(w.Code, ShouldEqual, http.StatusNotModified)
+			})
+			Convey("reject invalid indexes.", func() {
+				So(do(h, "GET", BaseURI+"v1/digit/x", "").Code, ShouldEqual, http.StatusBadRequest)
+				So(do(h, "GET", BaseURI+"v1/digit/-1", "").Code, ShouldEqual, http.StatusBadRequest)
+				w := do(h, "GET", BaseURI+"v1/digit/1000", "")
+				So(w.Code, ShouldEqual, http.StatusRequestedRangeNotSatisfiable)
+				So(w.Header().Get("ETag"), ShouldBeEmpty)
+				So(w.Header().Get("Cache-Control"), ShouldBeEmpty)
+			})
+		})
+
+		Convey("the chunk endpoint should", func() {
+			Convey("return JSON by default.", func() {
+				w := do(h, "GET", BaseURI+"v1/chunk/1/5", "")
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Header().Get("Content-Type"), ShouldEqual, mediaTypeJSON)
+				So(w.Header().Get("Vary"), ShouldEqual, "Accept")
+				var resp ChunkResponse
+				So(json.Unmarshal(w.Body.Bytes(), &resp), ShouldBeNil)
+				So(resp.FirstIndex, ShouldEqual, 1)
+				So(resp.Digits, ShouldResemble, []int{1, 4, 1, 5, 9})
+			})
+			Convey("honor the Accept header.", func() {
+				w := do(h, "GET", BaseURI+"v1/chunk/1/5", "", "Accept", "text/plain")
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldEqual, "14159")
+
+				w = do(h, "GET", BaseURI+"v1/chunk/1/5", "", "Accept", mediaTypeCompactJSON)
+				var resp CompactChunkResponse
+				So(json.Unmarshal(w.Body.Bytes(), &resp), ShouldBeNil)
+				So(resp.Digits, ShouldEqual, "14159")
+
+				w = do(h, "GET", BaseURI+"v1/chunk/1/5", "", "Accept", mediaTypeBinary)
+				So(w.Header().Get("X-First-Index"), ShouldEqual, "1")
+				So(w.Header().Get("X-Digit-Count"), ShouldEqual, "5")
+				So(w.Body.Bytes(), ShouldResemble, []byte{0x14, 0x15, 0x90})
+
+				w = do(h, "GET", BaseURI+"v1/chunk/1/5", "", "Accept", mediaTypeCBOR)
+				So(w.Body.Bytes(), ShouldResemble, encodeCBORChunk(1, []byte{1, 4, 1, 5, 9}))
+			})
+			Convey("use different ETags for different representations.", func() {
+				w := do(h, "GET", BaseURI+"v1/chunk/0/5", "", "Accept", "text/plain")
+				etag := w.Header().Get("ETag")
+				So(etag, ShouldNotBeEmpty)
+				So(do(h, "GET", BaseURI+"v1/chunk/0/5", "", "Accept", "text/plain", "If-None-Match", etag).Code, ShouldEqual, http.StatusNotModified)
+				So(do(h, "GET", BaseURI+"v1/chunk/0/5", "", "If-None-Match", etag).Code, ShouldEqual, http.StatusOK)
+			})
+			Convey("map the errors to status codes.", func() {
+				So(do(h, "GET", BaseURI+"v1/chunk/x/5", "").Code, ShouldEqual, http.StatusBadRequest)
+				So(do(h, "GET", BaseURI+"v1/chunk/0/x", "").Code, ShouldEqual, http.StatusBadRequest)
+				So(do(h, "GET", BaseURI+"v1/chunk/0/5", "", "Accept", "image/png").Code, ShouldEqual, http.StatusNotAcceptable)
+				So(do(h, "GET", BaseURI+"v1/chunk/0/65", "").Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+				So(do(h, "GET", BaseURI+"v1/chunk/1000/5", "").Code, ShouldEqual, http.StatusRequestedRangeNotSatisfiable)
+			})
+		})
+
+		Convey("the stream endpoint should", func() {
+			Convey("write text.", func() {
+				w := do(h, "GET", BaseURI+"v1/stream/10/300", "")
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Body.String(), ShouldEqual, text[10:310])
+			})
+			Convey("write binary digits.", func() {
+				w := do(h, "GET", BaseURI+"v1/stream/0/3", "", "Accept", mediaTypeBinary)
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Header().Get("X-Digit-Count"), ShouldEqual, "3")
+				So(w.Body.Bytes(), ShouldResemble, []byte{0x31, 0x40})
+			})
+			Convey("map the errors to status codes.", func() {
+				So(do(h, "GET", BaseURI+"v1/stream/x/5", "").Code, ShouldEqual, http.StatusBadRequest)
+				So(do(h, "GET", BaseURI+"v1/stream/0/0", "").Code, ShouldEqual, http.StatusBadRequest)
+				So(do(h, "GET", BaseURI+"v1/stream/0/5", "", "Accept", mediaTypeJSON).Code, ShouldEqual, http.StatusNotAcceptable)
+				So(do(h, "GET", BaseURI+"v1/stream/0/501", "").Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+				So(do(h, "GET", BaseURI+"v1/stream/900/200", "").Code, ShouldEqual, http.StatusRequestedRangeNotSatisfiable)
+			})
+			Convey("answer If-None-Match.", func() {
+				w := do(h, "GET", BaseURI+"v1/stream/0/5", "")
+				So(do(h, "GET", BaseURI+"v1/stream/0/5", "", "If-None-Match", w.Header().Get("ETag")).Code, ShouldEqual, http.StatusNotModified)
+			})
+		})
+
+		Convey("the hex endpoint should", func() {
+			Convey("return hexadecimal digits.", func() {
+				w := do(h, "GET", BaseURI+"v1/hex/0/4", "")
+				So(w.Code, ShouldEqual, http.StatusOK)
+				var resp HexResponse
+				So(json.Unmarshal(w.Body.Bytes(), &resp), ShouldBeNil)
+				So(resp.Digits, ShouldEqual, "3243")
+			})
+			Convey("map the errors to status codes.", func() {
+				So(do(h, "GET", BaseURI+"v1/hex/x/4", "").Code, ShouldEqual, http.StatusBadRequest)
+				So(do(h, "GET", BaseURI+"v1/hex/0/17", "").Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+			})
+		})
+
+		Convey("the search endpoint should", func() {
+			Convey("find sequences.", func() {
+				w := do(h, "GET", BaseURI+"v1/search/14159?limit=1", "")
+				So(w.Code, ShouldEqual, http.StatusOK)
+				var resp SearchResponse
+				So(json.Unmarshal(w.Body.Bytes(), &resp), ShouldBeNil)
+				So(resp.FirstOccurrence, ShouldEqual, 1)
+				So(resp.Positions, ShouldResemble, []int64{1})
+			})
+			Convey("reject invalid requests.", func() {
+				So(do(h, "GET", BaseURI+"v1/search/14a", "").Code, ShouldEqual, http.StatusBadRequest)
+				So(do(h, "GET", BaseURI+"v1/search/14?offset=x", "").Code, ShouldEqual, http.StatusBadRequest)
+				So(do(h, "GET", BaseURI+"v1/search/14?limit=1001", "").Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+
+		Convey("the first occurrence endpoint should", func() {
+			Convey("find sequences.", func() {
+				w := do(h, "GET", BaseURI+"v1/first/2653", "")
+				So(w.Code, ShouldEqual, http.StatusOK)
+				var resp FirstOccurrenceResponse
+				So(json.Unmarshal(w.Body.Bytes(), &resp), ShouldBeNil)
+				So(resp.FirstOccurrence, ShouldEqual, 6)
+			})
+			Convey("reject invalid sequences.", func() {
+				So(do(h, "GET", BaseURI+"v1/first/x", "").Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+
+		Convey("the stats endpoint should", func() {
+			Convey("count the digits.", func() {
+				w := do(h, "GET", BaseURI+"v1/stats/0/10", "")
+				So(w.Code, ShouldEqual, http.StatusOK)
+				var resp StatsResponse
+				So(json.Unmarshal(w.Body.Bytes(), &resp), ShouldBeNil)
+				So(resp.Counts, ShouldResemble, [10]int64{0, 2, 1, 2, 1, 2, 1, 0, 0, 1})
+			})
+			Convey("map the errors to status codes.", func() {
+				So(do(h, "GET", BaseURI+"v1/stats/x/10", "").Code, ShouldEqual, http.StatusBadRequest)
+				So(do(h, "GET", BaseURI+"v1/stats/0/501", "").Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+				So(do(h, "GET", BaseURI+"v1/stats/900/200", "").Code, ShouldEqual, http.StatusRequestedRangeNotSatisfiable)
+			})
+		})
+
+		Convey("the batch endpoint should", func() {
+			Convey("return the digits of all items.", func() {
+				w := do(h, "POST", BaseURI+"v2/batch", `{"items": [5, {"firstIndex": 1, "size": 4}, 1000]}`)
+				So(w.Code, ShouldEqual, http.StatusOK)
+				var resp BatchResponse
+				So(json.Unmarshal(w.Body.Bytes(), &resp), ShouldBeNil)
+				So(resp.Results, ShouldHaveLength, 3)
+				So(resp.Results[0].Digits, ShouldEqual, "9")
+				So(resp.Results[1].Digits, ShouldEqual, "1415")
+				So(resp.Results[2].Error, ShouldNotBeNil)
+			})
+			Convey("map the errors to status codes.", func() {
+				So(do(h, "POST", BaseURI+"v2/batch", `{"items": [`).Code, ShouldEqual, http.StatusBadRequest)
+				So(do(h, "POST", BaseURI+"v2/batch", `{"items": [{"firstIndex": 1}]}`).Code, ShouldEqual, http.StatusBadRequest)
+				So(do(h, "POST", BaseURI+"v2/batch", `{"items": [{"firstIndex": 0, "size": 101}]}`).Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+				So(do(h, "POST", BaseURI+"v2/batch", strings.Repeat(" ", maxBatchBodySize+1)).Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+			})
+		})
+
+		Convey("the settings endpoint should return the settings.", func() {
+			w := do(h, "GET", BaseURI+"v1/settings", "")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			var resp SettingsResponse
+			So(json.Unmarshal(w.Body.Bytes(), &resp), ShouldBeNil)
+			So(resp.AvailableDigits, ShouldEqual, 1000)
+			So(resp.MaximumChunkSize, ShouldEqual, 64)
+		})
+	})
+
+	Convey("Given an API with a separate stream source", t, func() {
+		source := &piiotest.MemoryChunkSource{Digits: digits, MaxSize: StreamBlockSize}
+		h := NewAPI(&piiotest.MemoryChunkSource{Digits: digits, MaxSize: 64}, WithStreamSource(source)).Handler()
+
+		Convey("streams should be read in large blocks.", func() {
+			w := do(h, "GET", BaseURI+"v1/stream/0/500", "")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldEqual, text[:500])
+			So(source.Calls(), ShouldEqual, 1)
+		})
+	})
+
+	Convey("Given an API reading too slowly", t, func() {
+		h := NewAPI(&slowChunkSource{piiotest.MemoryChunkSource{Digits: digits, MaxSize: 64}}).Handler()
+		get := func(path string) int {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", path, nil).WithContext(ctx))
+			return w.Code
+		}
+
+		Convey("requests should time out.", func() {
+			So(get(BaseURI+"v1/digit/0"), ShouldEqual, http.StatusGatewayTimeout)
+			So(get(BaseURI+"v1/chunk/0/5"), ShouldEqual, http.StatusGatewayTimeout)
+			So(get(BaseURI+"v1/stats/0/5"), ShouldEqual, http.StatusGatewayTimeout)
+			So(get(BaseURI+"v1/first/14"), ShouldEqual, http.StatusGatewayTimeout)
+		})
+	})
+}
@@ -0,0 +1,249 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/targodan/piio"
+)
+
+// DefaultMaximumBatchSize is the default maximum amount of
+// digits requested per batch.
+const DefaultMaximumBatchSize = 1 << 20
+
+// maxBatchBodySize is the maximum size of the body of a
+// batch request in bytes.
+const maxBatchBodySize = 8 << 20
+
+// BatchItem is a single digit or range of digits of a batch.
+// In JSON it is either the index of a single digit or an
+// object with the keys "firstIndex" and "size".
+type BatchItem struct {
+	FirstIndex int64 `json:"firstIndex"`
+	Size       int64 `json:"size"`
+}
+
+func (item *BatchItem) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var r struct {
+			FirstIndex *int64 `json:"firstIndex"`
+			Size       *int64 `json:"size"`
+		}
+		err := json.Unmarshal(data, &r)
+		if err != nil {
+			return err
+		}
+		if r.FirstIndex == nil || r.Size == nil {
+			return &piio.InvalidArgumentError{Reason: "ranges must have a firstIndex and a size"}
+		}
+		item.FirstIndex, item.Size = *r.FirstIndex, *r.Size
+		return nil
+	}
+	item.Size = 1
+	return json.Unmarshal(data, &item.FirstIndex)
+}
+
+func (item *BatchItem) end() int64 {
+	return item.FirstIndex + item.Size
+}
+
+// BatchResult holds the digits of a BatchItem or the reason
+// they could not be read.
+type BatchResult struct {
+	FirstIndex int64   `json:"firstIndex"`
+	Digits     string  `json:"digits"`
+	Error      *string `json:"error"`
+}
+
+func (api *API) Batch(items []BatchItem) ([]BatchResult, error) {
+	return api.BatchContext(context.Background(), items)
+}
+
+// BatchContext reads the digits of all items, returning the
+// results in the same order. Overlapping and nearby items are
+// read together, so that as few chunks as possible are read.
+// Invalid items and failed reads only fail the affected items,
+// while a batch requesting too many digits fails as a whole.
+func (api *API) BatchContext(ctx context.Context, items []BatchItem) ([]BatchResult, error) {
+	avail, err := api.chunkSource.AvailableDigits()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BatchResult, len(items))
+	digits := make([][]byte, len(items))
+	var valid []int
+	var total int64
+	for i := range items {
+		results[i].FirstIndex = items[i].FirstIndex
+		err := checkBatchItem(&items[i], avail)
+		if err != nil {
+			setBatchError(&results[i], err)
+			continue
+		}
+		total += items[i].Size
+		if total > api.maxBatchSize {
+			return nil, &piio.ChunkTooLargeError{
+				Size:        int(total),
+				MaximumSize: int(api.maxBatchSize),
+			}
+		}
+		valid = append(valid, i)
+	}
+	sort.Slice(valid, func(a, b int) bool {
+		return items[valid[a]].FirstIndex < items[valid[b]].FirstIndex
+	})
+	for _, i := range valid {
+		digits[i] = make([]byte, items[i].Size)
+	}
+
+	reads, err := api.batchReads(items, valid, avail)
+	if err != nil {
+		return nil, err
+	}
+
+	// The items are filled read by read, so only the digits of
+	// a single read are held in addition to the results.
+	var active []int
+	next := 0
+	for _, read := range reads {
+		readEnd := read.FirstIndex + read.Size
+		for next < len(valid) && items[valid[next]].FirstIndex < readEnd {
+			active = append(active, valid[next])
+			next++
+		}
+
+		chnk, err := api.chunkSource.GetChunkContext(ctx, read.FirstIndex, int(read.Size))
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		if err == nil && int64(chnk.Length()) != read.Size {
+			err = &piio.CorruptDataError{Index: read.FirstIndex + int64(chnk.Length()), Reason: "fewer digits than available"}
+		}
+		var unChnk *piio.UncompressedChunk
+		if err == nil {
+			unChnk = piio.AsUncompressedChunk(chnk)
+		}
+
+		remaining := active[:0]
+		for _, i := range active {
+			if err != nil {
+				if results[i].Error == nil {
+					setBatchError(&results[i], err)
+				}
+			} else {
+				first, end := max64(items[i].FirstIndex, read.FirstIndex), min64(items[i].end(), readEnd)
+				if first < end {
+					copy(digits[i][first-items[i].FirstIndex:], unChnk.Digits[first-read.FirstIndex:end-read.FirstIndex])
+				}
+			}
+			if items[i].end() > readEnd {
+				remaining = append(remaining, i)
+			}
+		}
+		active = remaining
+	}
+
+	for _, i := range valid {
+		if results[i].Error != nil {
+			continue
+		}
+		for j := range digits[i] {
+			digits[i][j] += '0'
+		}
+		results[i].Digits = string(digits[i])
+	}
+	return results, nil
+}
+
+// batchReads coalesces the items into reads. Computed digits
+// are read separately from the stored ones, as the fallback
+// source limits the size of its own chunks.
+func (api *API) batchReads(items []BatchItem, sorted []int, avail int64) ([]piio.Range, error) {
+	maxChunkSize := int64(api.chunkSource.MaximumChunkSize())
+	fs, ok := api.chunkSource.(*piio.FallbackChunkSource)
+	if !ok {
+		return coalesce(items, sorted, piio.Range{FirstIndex: 0, Size: avail}, maxChunkSize), nil
+	}
+
+	ranges, err := fs.Ranges()
+	if err != nil {
+		return nil, err
+	}
+	maxComputedSize := int64(fs.Fallback().MaximumChunkSize())
+	if maxComputedSize > maxChunkSize {
+		maxComputedSize = maxChunkSize
+	}
+	reads := coalesce(items, sorted, ranges.Stored, maxChunkSize)
+	return append(reads, coalesce(items, sorted, ranges.Fallback, maxComputedSize)...), nil
+}
+
+// checkBatchItem checks whether the digits of the item are
+// available.
+func checkBatchItem(item *BatchItem, avail int64) error {
+	if item.FirstIndex < 0 || item.Size <= 0 {
+		return &piio.InvalidArgumentError{Reason: "the first index must not be negative and the size must be positive"}
+	}
+	if item.Size > avail-item.FirstIndex {
+		return &piio.OutOfRangeError{
+			Requested: piio.Range{FirstIndex: item.FirstIndex, Size: item.Size},
+			Available: piio.Range{FirstIndex: 0, Size: avail},
+		}
+	}
+	return nil
+}
+
+func setBatchError(result *BatchResult, err error) {
+	errMsg := err.Error()
+	result.Error = &errMsg
+}
+
+// coalesce returns the reads covering the digits of the given
+// items within the given range. The items have to be sorted
+// by their first index. Each read is extended up to the
+// maximum chunk size as long as that covers the next item,
+// so the amount of reads is minimal.
+func coalesce(items []BatchItem, sorted []int, within piio.Range, maxChunkSize int64) []piio.Range {
+	var reads []piio.Range
+	for _, i := range sorted {
+		pos := max64(items[i].FirstIndex, within.FirstIndex)
+		end := min64(items[i].end(), within.FirstIndex+within.Size)
+		for pos < end {
+			if n := len(reads); n > 0 {
+				last := &reads[n-1]
+				lastEnd := last.FirstIndex + last.Size
+				if pos < lastEnd {
+					pos = lastEnd
+					continue
+				}
+				if pos < last.FirstIndex+maxChunkSize {
+					last.Size = min64(end, last.FirstIndex+maxChunkSize) - last.FirstIndex
+					pos = last.FirstIndex + last.Size
+					continue
+				}
+			}
+			size := min64(end-pos, maxChunkSize)
+			reads = append(reads, piio.Range{FirstIndex: pos, Size: size})
+			pos += size
+		}
+	}
+	return reads
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,105 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/targodan/piio"
+	"github.com/targodan/piio/internal/piiotest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCoalesce(t *testing.T) {
+	all := piio.Range{FirstIndex: 0, Size: 1000}
+
+	Convey("Coalescing the reads of a batch should", t, func() {
+		Convey("merge nearby items.", func() {
+			items := []BatchItem{{0, 1}, {3, 2}, {10, 1}, {100, 5}}
+			So(coalesce(items, []int{0, 1, 2, 3}, all, 8), ShouldResemble, []piio.Range{
+				{FirstIndex: 0, Size: 5},
+				{FirstIndex: 10, Size: 1},
+				{FirstIndex: 100, Size: 5},
+			})
+		})
+		Convey("split items larger than a chunk.", func() {
+			items := []BatchItem{{0, 4}, {2, 20}}
+			So(coalesce(items, []int{0, 1}, all, 8), ShouldResemble, []piio.Range{
+				{FirstIndex: 0, Size: 8},
+				{FirstIndex: 8, Size: 8},
+				{FirstIndex: 16, Size: 6},
+			})
+		})
+		Convey("read duplicate and contained items once.", func() {
+			items := []BatchItem{{4, 1}, {1, 6}, {4, 1}}
+			So(coalesce(items, []int{1, 0, 2}, all, 8), ShouldResemble, []piio.Range{
+				{FirstIndex: 1, Size: 6},
+			})
+		})
+		Convey("only read the digits within the range.", func() {
+			items := []BatchItem{{0, 10}, {20, 5}}
+			So(coalesce(items, []int{0, 1}, piio.Range{FirstIndex: 5, Size: 17}, 8), ShouldResemble, []piio.Range{
+				{FirstIndex: 5, Size: 5},
+				{FirstIndex: 20, Size: 2},
+			})
+		})
+		Convey("read nothing for no items.", func() {
+			So(coalesce(nil, nil, all, 8), ShouldBeEmpty)
+		})
+	})
+}
+
+func TestBatch(t *testing.T) {
+	text, digits := testDigits(t, 100)
+	source := &piiotest.MemoryChunkSource{Digits: digits, MaxSize: 16}
+	api := NewAPI(source, WithMaximumBatchSize(50))
+
+	Convey("Given a batch", t, func() {
+		var req BatchRequest
+		err := json.Unmarshal([]byte(`{"items": [90, {"firstIndex": 2, "size": 30}, 3, -1, {"firstIndex": 99, "size": 2}]}`), &req)
+		So(err, ShouldBeNil)
+
+		Convey("all items should be read in as few chunks as possible.", func() {
+			results, err := api.BatchContext(context.Background(), req.Items)
+			So(err, ShouldBeNil)
+			So(results, ShouldHaveLength, 5)
+			So(results[0].Digits, ShouldEqual, text[90:91])
+			So(results[1].Digits, ShouldEqual, text[2:32])
+			So(results[2].Digits, ShouldEqual, text[3:4])
+			So(results[3].Error, ShouldNotBeNil)
+			So(results[4].Error, ShouldNotBeNil)
+			So(results[4].FirstIndex, ShouldEqual, 99)
+			So(source.Calls(), ShouldEqual, 3)
+		})
+		Convey("too many digits should fail the batch.", func() {
+			_, err := api.BatchContext(context.Background(), append(req.Items, BatchItem{FirstIndex: 0, Size: 20}))
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given a file with computed digits following it", t, func() {
+		text, _ := testDigits(t, 120)
+		file, err := ioutil.TempFile("", "piio-rest")
+		So(err, ShouldBeNil)
+		defer os.Remove(file.Name())
+		_, err = file.WriteString(text[:100])
+		So(err, ShouldBeNil)
+		So(file.Close(), ShouldBeNil)
+
+		cs, err := piio.Open(file.Name(), piio.WithMaximumChunkSize(16), piio.WithComputedDigits(120, 4, 0))
+		So(err, ShouldBeNil)
+		api := NewAPI(cs)
+
+		Convey("reads past the stored digits should respect the computation limit.", func() {
+			results, err := api.BatchContext(context.Background(), []BatchItem{{95, 10}, {110, 3}, {2, 3}})
+			So(err, ShouldBeNil)
+			for i, r := range []piio.Range{{FirstIndex: 95, Size: 10}, {FirstIndex: 110, Size: 3}, {FirstIndex: 2, Size: 3}} {
+				So(results[i].Error, ShouldBeNil)
+				So(results[i].Digits, ShouldEqual, text[r.FirstIndex:r.FirstIndex+r.Size])
+			}
+		})
+	})
+}
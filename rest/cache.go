@@ -0,0 +1,140 @@
+package rest
+
+import (
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/targodan/piio"
+)
+
+// cacheControl is sent along with all digits, as they
+// never change.
+const cacheControl = "public, max-age=31536000, immutable"
+
+// RawFile is the file of digits served as it is by the raw
+// endpoint. It also identifies the served digits in the ETags
+// of all other responses containing digits.
+type RawFile struct {
+	file   *os.File
+	format piio.FileFormat
+	size   int64
+	// tag is the unquoted strong ETag of the file.
+	tag string
+}
+
+// OpenRawFile opens the file at the given path. Its ETag is
+// derived from the checksum in its header or, if it has none,
+// from the CRC-32 checksum of the whole file. Only files in
+// FileFormatCompressed and FileFormatDense are served by the
+// raw endpoint, as the bytes of other files are not the
+// packed digits.
+func OpenRawFile(path string, format piio.FileFormat) (*RawFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := newRawFile(file, format)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func newRawFile(file *os.File, format piio.FileFormat) (*RawFile, error) {
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var checksum uint32
+	h, err := piio.ReadHeader(file)
+	if err == nil {
+		checksum = h.Checksum
+	} else if err == piio.ErrNoHeader {
+		crc := crc32.NewIEEE()
+		_, err = io.Copy(crc, io.NewSectionReader(file, 0, fi.Size()))
+		if err != nil {
+			return nil, err
+		}
+		checksum = crc.Sum32()
+	} else {
+		return nil, err
+	}
+
+	return &RawFile{
+		file:   file,
+		format: format,
+		size:   fi.Size(),
+		tag:    fmt.Sprintf("%08x-%x", checksum, fi.Size()),
+	}, nil
+}
+
+// ETag returns the quoted strong ETag of the file.
+func (f *RawFile) ETag() string {
+	return `"` + f.tag + `"`
+}
+
+// Close closes the file.
+func (f *RawFile) Close() error {
+	return f.file.Close()
+}
+
+// serve writes the requested ranges of the file. Files not
+// holding packed digits are reported as not found.
+func (f *RawFile) serve(w http.ResponseWriter, r *http.Request) {
+	if f.format != piio.FileFormatCompressed && f.format != piio.FileFormatDense {
+		errMsg := fmt.Sprintf("the raw file is only available for compressed and dense files, the digits are stored as %s", f.format)
+		writeJsonStatus(w, http.StatusNotFound, &RawResponse{Error: &errMsg})
+		return
+	}
+	w.Header().Set("Content-Type", mediaTypeBinary)
+	w.Header().Set("ETag", f.ETag())
+	w.Header().Set("Cache-Control", cacheControl)
+	// A SectionReader does not share the offset of the file
+	// between concurrent requests.
+	http.ServeContent(w, r, "", time.Time{}, io.NewSectionReader(f.file, 0, f.size))
+}
+
+// notModified sets the caching headers of a response
+// containing digits. If the client already holds the same
+// representation, it writes 304 Not Modified and returns
+// true. The variant distinguishes the representations of
+// the same URL.
+func (api *API) notModified(w http.ResponseWriter, r *http.Request, variant string) bool {
+	w.Header().Set("Cache-Control", cacheControl)
+	if api.rawFile == nil {
+		return false
+	}
+
+	h := fnv.New64a()
+	io.WriteString(h, r.URL.Path)
+	h.Write([]byte{0})
+	io.WriteString(h, variant)
+	etag := fmt.Sprintf(`"%s-%016x"`, api.rawFile.tag, h.Sum64())
+	w.Header().Set("ETag", etag)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// etagMatches returns true if the ETag is in the list of an
+// If-None-Match header, using the weak comparison.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
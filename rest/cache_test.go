@@ -0,0 +1,94 @@
+package rest
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/targodan/piio"
+	"github.com/targodan/piio/internal/piiotest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRawFile(t *testing.T) {
+	_, digits := testDigits(t, 100)
+	raw := writeRawFile(t, digits, piio.FileFormatCompressed)
+	defer raw.Close()
+	file, err := ioutil.ReadAll(io.NewSectionReader(raw.file, 0, raw.size))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewAPI(&piiotest.MemoryChunkSource{Digits: digits, MaxSize: 64}, WithRawFile(raw)).Handler()
+
+	Convey("Given an API serving a compressed file", t, func() {
+		Convey("the whole file should be served.", func() {
+			w := do(h, "GET", BaseURI+"v1/raw", "")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.Bytes(), ShouldResemble, file)
+			So(w.Header().Get("ETag"), ShouldEqual, raw.ETag())
+			So(w.Header().Get("Cache-Control"), ShouldEqual, cacheControl)
+			So(w.Header().Get("Accept-Ranges"), ShouldEqual, "bytes")
+
+			w = do(h, "HEAD", BaseURI+"v1/raw", "")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.Len(), ShouldEqual, 0)
+		})
+		Convey("single ranges should be served.", func() {
+			w := do(h, "GET", BaseURI+"v1/raw", "", "Range", "bytes=2-5")
+			So(w.Code, ShouldEqual, http.StatusPartialContent)
+			So(w.Body.Bytes(), ShouldResemble, file[2:6])
+		})
+		Convey("multiple ranges should be served.", func() {
+			w := do(h, "GET", BaseURI+"v1/raw", "", "Range", "bytes=0-1,10-11")
+			So(w.Code, ShouldEqual, http.StatusPartialContent)
+			So(w.Header().Get("Content-Type"), ShouldStartWith, "multipart/byteranges")
+			So(w.Body.String(), ShouldContainSubstring, string(file[10:12]))
+		})
+		Convey("unsatisfiable ranges should be rejected.", func() {
+			w := do(h, "GET", BaseURI+"v1/raw", "", "Range", "bytes=1000-")
+			So(w.Code, ShouldEqual, http.StatusRequestedRangeNotSatisfiable)
+		})
+		Convey("If-None-Match should be answered.", func() {
+			w := do(h, "GET", BaseURI+"v1/raw", "", "If-None-Match", raw.ETag())
+			So(w.Code, ShouldEqual, http.StatusNotModified)
+		})
+		Convey("If-Range should fall back to the whole file for other ETags.", func() {
+			w := do(h, "GET", BaseURI+"v1/raw", "", "Range", "bytes=2-5", "If-Range", `"other"`)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.Bytes(), ShouldResemble, file)
+		})
+	})
+
+	Convey("Given an API with a file that does not hold packed digits", t, func() {
+		text := writeRawFile(t, digits, piio.FileFormatText)
+		defer text.Close()
+		h := NewAPI(&piiotest.MemoryChunkSource{Digits: digits, MaxSize: 64}, WithRawFile(text)).Handler()
+
+		Convey("the raw file should not be found.", func() {
+			w := do(h, "GET", BaseURI+"v1/raw", "")
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+			So(w.Body.String(), ShouldContainSubstring, "error")
+		})
+		Convey("other responses should still be cacheable.", func() {
+			w := do(h, "GET", BaseURI+"v1/digit/0", "")
+			So(w.Header().Get("ETag"), ShouldStartWith, strings.TrimSuffix(text.ETag(), `"`))
+		})
+	})
+}
+
+func TestETagMatches(t *testing.T) {
+	Convey("Matching If-None-Match headers should", t, func() {
+		Convey("accept listed and weak ETags.", func() {
+			So(etagMatches(`"a", "b"`, `"b"`), ShouldBeTrue)
+			So(etagMatches(`W/"b"`, `"b"`), ShouldBeTrue)
+			So(etagMatches(`*`, `"b"`), ShouldBeTrue)
+		})
+		Convey("reject other ETags.", func() {
+			So(etagMatches(`"a"`, `"b"`), ShouldBeFalse)
+			So(etagMatches(``, `"b"`), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,49 @@
+package rest
+
+// CBOR major types, see RFC 8949.
+const (
+	cborUnsignedInt = 0
+	cborByteString  = 2
+	cborTextString  = 3
+	cborMap         = 5
+)
+
+// appendCBORHead appends the head of a CBOR data item of the
+// given major type and argument.
+func appendCBORHead(buf []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(buf, major|byte(n))
+	case n <= 0xff:
+		return append(buf, major|24, byte(n))
+	case n <= 0xffff:
+		return append(buf, major|25, byte(n>>8), byte(n))
+	case n <= 0xffffffff:
+		return append(buf, major|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(buf, major|27, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32),
+		byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func appendCBORText(buf []byte, s string) []byte {
+	buf = appendCBORHead(buf, cborTextString, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func appendCBORBytes(buf []byte, b []byte) []byte {
+	buf = appendCBORHead(buf, cborByteString, uint64(len(b)))
+	return append(buf, b...)
+}
+
+// encodeCBORChunk encodes a chunk as a CBOR map with the keys
+// "firstIndex" and "digits", the latter being a byte string
+// with one digit per byte.
+func encodeCBORChunk(firstIndex int64, digits []byte) []byte {
+	buf := make([]byte, 0, len(digits)+32)
+	buf = appendCBORHead(buf, cborMap, 2)
+	buf = appendCBORText(buf, "firstIndex")
+	buf = appendCBORHead(buf, cborUnsignedInt, uint64(firstIndex))
+	buf = appendCBORText(buf, "digits")
+	return appendCBORBytes(buf, digits)
+}
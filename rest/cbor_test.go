@@ -0,0 +1,28 @@
+package rest
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEncodeCBORChunk(t *testing.T) {
+	Convey("Encoding a chunk as CBOR should", t, func() {
+		Convey("yield a map of the first index and the digits.", func() {
+			expected := []byte{0xA2, 0x6A}
+			expected = append(expected, "firstIndex"...)
+			expected = append(expected, 0x05, 0x66)
+			expected = append(expected, "digits"...)
+			expected = append(expected, 0x43, 3, 1, 4)
+			So(encodeCBORChunk(5, []byte{3, 1, 4}), ShouldResemble, expected)
+		})
+		Convey("use the shortest head for the arguments.", func() {
+			So(appendCBORHead(nil, cborUnsignedInt, 23), ShouldResemble, []byte{0x17})
+			So(appendCBORHead(nil, cborUnsignedInt, 24), ShouldResemble, []byte{0x18, 0x18})
+			So(appendCBORHead(nil, cborUnsignedInt, 1000), ShouldResemble, []byte{0x19, 0x03, 0xE8})
+			So(appendCBORHead(nil, cborUnsignedInt, 1000000), ShouldResemble, []byte{0x1A, 0x00, 0x0F, 0x42, 0x40})
+			So(appendCBORHead(nil, cborUnsignedInt, 1<<32), ShouldResemble, []byte{0x1B, 0, 0, 0, 1, 0, 0, 0, 0})
+			So(appendCBORHead(nil, cborByteString, 300), ShouldResemble, []byte{0x59, 0x01, 0x2C})
+		})
+	})
+}
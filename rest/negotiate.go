@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	mediaTypeJSON        = "application/json"
+	mediaTypeCompactJSON = "application/vnd.piio.compact+json"
+	mediaTypeText        = "text/plain"
+	mediaTypeBinary      = "application/octet-stream"
+	mediaTypeCBOR        = "application/cbor"
+)
+
+// chunkMediaTypes are the media types chunks can be served
+// as, in order of preference.
+var chunkMediaTypes = []string{
+	mediaTypeJSON,
+	mediaTypeCompactJSON,
+	mediaTypeText,
+	mediaTypeBinary,
+	mediaTypeCBOR,
+}
+
+// streamMediaTypes are the media types streams can be
+// served as, in order of preference.
+var streamMediaTypes = []string{
+	mediaTypeText,
+	mediaTypeBinary,
+}
+
+// mediaRange is a single entry of an Accept header.
+type mediaRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// matches returns how specifically the range matches the
+// media type, or -1 if it does not match it at all.
+func (r *mediaRange) matches(mediaType string) int {
+	typ, subtype := splitMediaType(mediaType)
+	switch {
+	case r.typ == typ && r.subtype == subtype:
+		return 2
+	case r.typ == typ && r.subtype == "*":
+		return 1
+	case r.typ == "*" && r.subtype == "*":
+		return 0
+	}
+	return -1
+}
+
+func splitMediaType(mediaType string) (string, string) {
+	i := strings.IndexByte(mediaType, '/')
+	if i < 0 {
+		return mediaType, ""
+	}
+	return mediaType[:i], mediaType[i+1:]
+}
+
+// parseAccept parses the media ranges of an Accept header.
+// Invalid entries are skipped.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, entry := range strings.Split(accept, ",") {
+		params := strings.Split(entry, ";")
+		typ, subtype := splitMediaType(strings.ToLower(strings.TrimSpace(params[0])))
+		if typ == "" || subtype == "" {
+			continue
+		}
+		r := mediaRange{typ: typ, subtype: subtype, q: 1}
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) != 2 || strings.ToLower(kv[0]) != "q" {
+				continue
+			}
+			q, err := strconv.ParseFloat(kv[1], 64)
+			if err == nil && q >= 0 && q <= 1 {
+				r.q = q
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// negotiate returns the offered media type preferred by the
+// Accept header, the first one if the header is empty, or an
+// empty string if none is acceptable.
+func negotiate(accept string, offers []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+	ranges := parseAccept(accept)
+
+	best := ""
+	bestQ := 0.0
+	for _, offer := range offers {
+		// The most specific matching range determines the
+		// quality of the offer.
+		specificity := -1
+		q := 0.0
+		for i := range ranges {
+			if s := ranges[i].matches(offer); s > specificity {
+				specificity = s
+				q = ranges[i].q
+			}
+		}
+		if q > bestQ {
+			best = offer
+			bestQ = q
+		}
+	}
+	return best
+}
@@ -0,0 +1,41 @@
+package rest
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNegotiate(t *testing.T) {
+	Convey("Negotiating the media type should", t, func() {
+		Convey("prefer the first offer without an Accept header.", func() {
+			So(negotiate("", chunkMediaTypes), ShouldEqual, mediaTypeJSON)
+			So(negotiate("", streamMediaTypes), ShouldEqual, mediaTypeText)
+		})
+		Convey("pick the offer with the highest quality.", func() {
+			So(negotiate("text/plain;q=0.5, application/cbor", chunkMediaTypes), ShouldEqual, mediaTypeCBOR)
+			So(negotiate("Application/CBOR; q=0.9, text/plain", chunkMediaTypes), ShouldEqual, mediaTypeText)
+		})
+		Convey("prefer earlier offers of the same quality.", func() {
+			So(negotiate("application/cbor, text/plain", chunkMediaTypes), ShouldEqual, mediaTypeText)
+		})
+		Convey("resolve wildcards.", func() {
+			So(negotiate("*/*", chunkMediaTypes), ShouldEqual, mediaTypeJSON)
+			So(negotiate("text/*", chunkMediaTypes), ShouldEqual, mediaTypeText)
+			So(negotiate("application/*", streamMediaTypes), ShouldEqual, mediaTypeBinary)
+		})
+		Convey("exclude offers with q=0.", func() {
+			So(negotiate("text/plain;q=0, */*", streamMediaTypes), ShouldEqual, mediaTypeBinary)
+			So(negotiate("*/*;q=0.1, application/json;q=0", chunkMediaTypes), ShouldEqual, mediaTypeCompactJSON)
+			So(negotiate("text/plain;q=0", chunkMediaTypes), ShouldEqual, "")
+			So(negotiate("*/*;q=0", chunkMediaTypes), ShouldEqual, "")
+		})
+		Convey("return nothing if no offer is acceptable.", func() {
+			So(negotiate("image/png", chunkMediaTypes), ShouldEqual, "")
+			So(negotiate("application/json", streamMediaTypes), ShouldEqual, "")
+		})
+		Convey("skip invalid entries.", func() {
+			So(negotiate("garbage, text/plain;q=x", chunkMediaTypes), ShouldEqual, mediaTypeText)
+		})
+	})
+}
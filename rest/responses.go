@@ -1,5 +1,7 @@
 package rest
 
+import "github.com/targodan/piio"
+
 type DigitResponse struct {
 	Index int64   `json:"index"`
 	Digit byte    `json:"digit"`
@@ -12,8 +14,66 @@ type ChunkResponse struct {
 	Error      *string `json:"error"`
 }
 
+// CompactChunkResponse is a ChunkResponse with the digits as
+// a single string.
+type CompactChunkResponse struct {
+	FirstIndex int64   `json:"firstIndex"`
+	Digits     string  `json:"digits"`
+	Error      *string `json:"error"`
+}
+
+type HexResponse struct {
+	FirstIndex int64   `json:"firstIndex"`
+	Digits     string  `json:"digits"`
+	Error      *string `json:"error"`
+}
+
+// RawResponse is only sent if the raw file cannot be
+// served. The file itself is sent as it is.
+type RawResponse struct {
+	Error *string `json:"error"`
+}
+
 type SettingsResponse struct {
-	AvailableDigits  int64   `json:"availableDigits"`
-	MaximumChunkSize int     `json:"maximumChunkSize"`
-	Error            *string `json:"error"`
+	AvailableDigits  int64       `json:"availableDigits"`
+	MaximumChunkSize int         `json:"maximumChunkSize"`
+	StoredDigits     piio.Range  `json:"storedDigits"`
+	ComputedDigits   *piio.Range `json:"computedDigits"`
+	Error            *string     `json:"error"`
+}
+
+type SearchResponse struct {
+	Sequence        string  `json:"sequence"`
+	FirstOccurrence int64   `json:"firstOccurrence"`
+	Count           int64   `json:"count"`
+	Offset          int     `json:"offset"`
+	Limit           int     `json:"limit"`
+	Positions       []int64 `json:"positions"`
+	Error           *string `json:"error"`
+}
+
+type FirstOccurrenceResponse struct {
+	Sequence        string  `json:"sequence"`
+	FirstOccurrence int64   `json:"firstOccurrence"`
+	Error           *string `json:"error"`
+}
+
+type StatsResponse struct {
+	FirstIndex int64     `json:"firstIndex"`
+	Size       int64     `json:"size"`
+	Counts     [10]int64 `json:"counts"`
+	Mean       float64   `json:"mean"`
+	ChiSquare  float64   `json:"chiSquare"`
+	Error      *string   `json:"error"`
+}
+
+// BatchRequest lists the digits and ranges of digits of a
+// batch.
+type BatchRequest struct {
+	Items []BatchItem `json:"items"`
+}
+
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+	Error   *string       `json:"error"`
 }
@@ -0,0 +1,235 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/targodan/piio"
+)
+
+// FirstOccurrenceVersion is the version of the first
+// occurrence tables written by this package.
+const FirstOccurrenceVersion = 1
+
+// MaximumTableLength is the length of the longest sequences
+// a first occurrence table can be built for.
+const MaximumTableLength = 9
+
+var firstOccurrenceMagic = []byte("PIIF")
+
+// firstOccurrenceHeaderSize is the size of the header of a
+// first occurrence table.
+const firstOccurrenceHeaderSize = 4 + 2 + 1 + 1 + 8
+
+// FirstOccurrenceTable holds the first position of every
+// sequence of a fixed length, so looking one up takes
+// constant time. The table is memory mapped where possible.
+//
+// The binary layout of the file is as follows, all numbers
+// are big endian.
+//
+//	4 bytes  magic "PIIF"
+//	uint16   version
+//	uint8    length of the sequences
+//	uint8    size of a position in bytes, 4 or 8
+//	uint64   amount of digits the table was built from
+//	entries  10^n positions of the given size, ordered by
+//	         the sequence, all bits set if it does not occur
+type FirstOccurrenceTable struct {
+	mapping    []byte
+	entries    []byte
+	length     int
+	width      int
+	digitCount int64
+}
+
+// BuildFirstOccurrenceTable writes the table of the first
+// occurrences of all sequences of the given length within the
+// digits of the source to w. The digits are read in a single
+// pass, which stops early once all sequences have been found.
+// The table is held in memory while building.
+func BuildFirstOccurrenceTable(ctx context.Context, w io.Writer, cs piio.ChunkSource, length int) error {
+	if length <= 0 || length > MaximumTableLength {
+		return &piio.InvalidArgumentError{Reason: fmt.Sprintf("the length must be between 1 and %d", MaximumTableLength)}
+	}
+	avail, err := cs.AvailableDigits()
+	if err != nil {
+		return err
+	}
+
+	width := 8
+	if avail < math.MaxUint32 {
+		width = 4
+	}
+	size := int64(1)
+	for i := 0; i < length; i++ {
+		size *= 10
+	}
+	header := make([]byte, firstOccurrenceHeaderSize)
+	copy(header, firstOccurrenceMagic)
+	binary.BigEndian.PutUint16(header[4:], FirstOccurrenceVersion)
+	header[6] = byte(length)
+	header[7] = byte(width)
+	binary.BigEndian.PutUint64(header[8:], uint64(avail))
+
+	entries := bytes.Repeat([]byte{0xff}, int(size)*width)
+	missing := size
+
+	blockSize := cs.MaximumChunkSize()
+	if blockSize > readBlockSize {
+		blockSize = readBlockSize
+	}
+	// value holds the last length digits read as a number.
+	var value int64
+	var pos int64
+	for pos < avail && missing > 0 {
+		chnk, err := piio.GetChunkContext(ctx, cs, pos, blockSize)
+		if err != nil {
+			return err
+		}
+		for _, d := range piio.AsUncompressedChunk(chnk).Digits {
+			if d > 9 {
+				return &piio.CorruptDataError{Index: pos, Reason: "invalid digit"}
+			}
+			value = (value*10 + int64(d)) % size
+			pos++
+			if pos < int64(length) {
+				continue
+			}
+			entry := entries[value*int64(width):]
+			if !unset(entry[:width]) {
+				continue
+			}
+			first := pos - int64(length)
+			if width == 4 {
+				binary.BigEndian.PutUint32(entry, uint32(first))
+			} else {
+				binary.BigEndian.PutUint64(entry, uint64(first))
+			}
+			missing--
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	_, err = bw.Write(header)
+	if err == nil {
+		_, err = bw.Write(entries)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// unset returns true if all bits of the entry are set.
+func unset(entry []byte) bool {
+	for _, b := range entry {
+		if b != 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+// OpenFirstOccurrenceTable opens the table at the given path.
+func OpenFirstOccurrenceTable(filename string) (*FirstOccurrenceTable, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header := make([]byte, firstOccurrenceHeaderSize)
+	_, err = io.ReadFull(file, header)
+	if err != nil || !bytes.Equal(header[:len(firstOccurrenceMagic)], firstOccurrenceMagic) {
+		return nil, &piio.UnsupportedFormatError{Reason: "not a first occurrence table"}
+	}
+	version := binary.BigEndian.Uint16(header[4:])
+	if version == 0 || version > FirstOccurrenceVersion {
+		return nil, &piio.UnsupportedFormatError{Reason: fmt.Sprintf("unsupported first occurrence table version %d", version)}
+	}
+
+	t := &FirstOccurrenceTable{
+		length:     int(header[6]),
+		width:      int(header[7]),
+		digitCount: int64(binary.BigEndian.Uint64(header[8:])),
+	}
+	if t.length == 0 || t.length > MaximumTableLength || (t.width != 4 && t.width != 8) || t.digitCount < 0 {
+		return nil, &piio.CorruptDataError{Index: -1, Reason: "invalid first occurrence table header"}
+	}
+	size := int64(t.width)
+	for i := 0; i < t.length; i++ {
+		size *= 10
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() != firstOccurrenceHeaderSize+size {
+		return nil, &piio.CorruptDataError{Index: -1, Reason: fmt.Sprintf("expected %d bytes of entries in the first occurrence table", size)}
+	}
+
+	t.mapping, err = mapTable(file, int(fi.Size()))
+	if err != nil {
+		return nil, err
+	}
+	t.entries = t.mapping[firstOccurrenceHeaderSize:]
+	return t, nil
+}
+
+// Length returns the length of the sequences in the table.
+func (t *FirstOccurrenceTable) Length() int {
+	return t.length
+}
+
+// DigitCount returns the amount of digits the table was
+// built from.
+func (t *FirstOccurrenceTable) DigitCount() int64 {
+	return t.digitCount
+}
+
+// Lookup returns the index of the first digit of the first
+// occurrence of the sequence or -1 if it does not occur. The
+// sequence has to be of the length of the table.
+func (t *FirstOccurrenceTable) Lookup(sequence []byte) (int64, error) {
+	if len(sequence) != t.length {
+		return 0, &piio.InvalidArgumentError{Reason: fmt.Sprintf("the sequence must be %d digits long", t.length)}
+	}
+	if t.entries == nil {
+		return 0, errors.New("the first occurrence table is closed")
+	}
+	var value int64
+	for _, d := range sequence {
+		if d > 9 {
+			return 0, &piio.InvalidArgumentError{Reason: "the sequence must only consist of decimal digits"}
+		}
+		value = value*10 + int64(d)
+	}
+
+	entry := t.entries[value*int64(t.width) : (value+1)*int64(t.width)]
+	if unset(entry) {
+		return -1, nil
+	}
+	if t.width == 4 {
+		return int64(binary.BigEndian.Uint32(entry)), nil
+	}
+	return int64(binary.BigEndian.Uint64(entry)), nil
+}
+
+// Close unmaps the table.
+func (t *FirstOccurrenceTable) Close() error {
+	if t.mapping == nil {
+		return nil
+	}
+	err := unmapTable(t.mapping)
+	t.mapping = nil
+	t.entries = nil
+	return err
+}
@@ -0,0 +1,549 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/targodan/piio"
+)
+
+// IndexVersion is the version of the index files written
+// by this package.
+const IndexVersion = 1
+
+// DefaultIndexDepth is the default amount of leading digits
+// by which the suffixes of an index are sorted.
+const DefaultIndexDepth = 32
+
+var indexMagic = []byte("PIIS")
+
+// indexHeaderSize is the size of the header of an index.
+const indexHeaderSize = 4 + 2 + 1 + 1 + 8
+
+// maxSortedPositions is the maximum amount of occurrences
+// read from the index and sorted by position. More frequent
+// sequences are found quickly by scanning the digits.
+const maxSortedPositions = 1 << 16
+
+// readBlockSize is the amount of digits read at once while
+// building an index.
+const readBlockSize = 1 << 20
+
+// bucketDigits is the amount of leading digits by which the
+// suffixes are distributed into buckets while building an
+// index.
+const bucketDigits = 3
+
+const bucketCount = 1000
+
+// sortPassSize is the amount of positions sorted at once
+// while building an index, which needs 8 bytes each. A pass
+// sorts as many buckets as fit into it.
+const sortPassSize = 1 << 24
+
+// Index is a Searcher using a truncated suffix array, i.e.
+// the positions of all digits sorted by the digits following
+// them up to a fixed depth. Searching takes logarithmic time.
+//
+// The binary layout of the file is as follows, all numbers
+// are big endian.
+//
+//	4 bytes  magic "PIIS"
+//	uint16   version
+//	uint8    size of a position in bytes, 4 or 8
+//	uint8    depth
+//	uint64   amount of digits
+//	n*size   sorted positions
+type Index struct {
+	file       *os.File
+	cs         piio.ChunkSource
+	width      int
+	depth      int
+	digitCount int64
+}
+
+// BuildIndex writes an index of all digits of the source to
+// w. The suffixes are sorted by their first depth digits.
+// While building, the digits are held in memory as half a
+// byte each and the positions are sorted in passes of
+// bounded size.
+func BuildIndex(ctx context.Context, w io.Writer, cs piio.ChunkSource, depth int) error {
+	return buildIndex(ctx, w, cs, depth, sortPassSize)
+}
+
+func buildIndex(ctx context.Context, w io.Writer, cs piio.ChunkSource, depth int, passSize int64) error {
+	if depth <= 0 || depth > math.MaxUint8 {
+		return &piio.InvalidArgumentError{Reason: fmt.Sprintf("the depth must be between 1 and %d", math.MaxUint8)}
+	}
+	digits, err := readPacked(ctx, cs)
+	if err != nil {
+		return err
+	}
+
+	width := 8
+	if digits.n <= math.MaxUint32 {
+		width = 4
+	}
+	header := make([]byte, indexHeaderSize)
+	copy(header, indexMagic)
+	binary.BigEndian.PutUint16(header[4:], IndexVersion)
+	header[6] = byte(width)
+	header[7] = byte(depth)
+	binary.BigEndian.PutUint64(header[8:], uint64(digits.n))
+
+	bw := bufio.NewWriter(w)
+	_, err = bw.Write(header)
+	if err != nil {
+		return err
+	}
+
+	var counts [bucketCount]int64
+	for pos := int64(0); pos < digits.n; pos++ {
+		counts[digits.bucket(pos)]++
+	}
+
+	buf := make([]byte, width)
+	for first := 0; first < bucketCount; {
+		// A pass sorts as many consecutive buckets as fit into
+		// it, but at least one.
+		last := first + 1
+		size := counts[first]
+		for last < bucketCount && size+counts[last] <= passSize {
+			size += counts[last]
+			last++
+		}
+		positions, err := sortSuffixes(ctx, digits, counts[first:last], first, depth)
+		if err != nil {
+			return err
+		}
+		for _, pos := range positions {
+			if width == 4 {
+				binary.BigEndian.PutUint32(buf, uint32(pos))
+			} else {
+				binary.BigEndian.PutUint64(buf, uint64(pos))
+			}
+			_, err = bw.Write(buf)
+			if err != nil {
+				return err
+			}
+		}
+		first = last
+	}
+	return bw.Flush()
+}
+
+// packedDigits holds digits in memory, two per byte.
+type packedDigits struct {
+	data []byte
+	n    int64
+}
+
+// readPacked reads all digits of the source into memory.
+func readPacked(ctx context.Context, cs piio.ChunkSource) (*packedDigits, error) {
+	avail, err := cs.AvailableDigits()
+	if err != nil {
+		return nil, err
+	}
+	blockSize := cs.MaximumChunkSize()
+	if blockSize > readBlockSize {
+		blockSize = readBlockSize
+	}
+
+	digits := &packedDigits{data: make([]byte, (avail+1)/2)}
+	for digits.n < avail {
+		chnk, err := piio.GetChunkContext(ctx, cs, digits.n, blockSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range piio.AsUncompressedChunk(chnk).Digits {
+			if d > 9 {
+				return nil, &piio.CorruptDataError{Index: digits.n, Reason: "invalid digit"}
+			}
+			if digits.n%2 == 0 {
+				digits.data[digits.n/2] = d << 4
+			} else {
+				digits.data[digits.n/2] |= d
+			}
+			digits.n++
+		}
+	}
+	return digits, nil
+}
+
+func (d *packedDigits) at(pos int64) byte {
+	b := d.data[pos/2]
+	if pos%2 == 0 {
+		return b >> 4
+	}
+	return b & 0x0F
+}
+
+// bucket returns the bucket of the suffix at pos, i.e. its
+// first bucketDigits digits as a number. Missing digits at
+// the end count as 0, which puts short suffixes in front of
+// the longer ones they are a prefix of.
+func (d *packedDigits) bucket(pos int64) int {
+	b := 0
+	for i := int64(0); i < bucketDigits; i++ {
+		b *= 10
+		if pos+i < d.n {
+			b += int(d.at(pos + i))
+		}
+	}
+	return b
+}
+
+// compare compares the first depth digits of the suffixes
+// at a and b like bytes.Compare.
+func (d *packedDigits) compare(a, b int64, depth int) int {
+	for i := int64(0); i < int64(depth); i++ {
+		switch {
+		case a+i >= d.n && b+i >= d.n:
+			return 0
+		case a+i >= d.n:
+			return -1
+		case b+i >= d.n:
+			return 1
+		}
+		x, y := d.at(a+i), d.at(b+i)
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// sortSuffixes returns the positions of the suffixes in the
+// consecutive buckets starting at the given one, which hold
+// the given amounts of positions. They are sorted by their
+// first depth digits, equal prefixes by position.
+func sortSuffixes(ctx context.Context, digits *packedDigits, counts []int64, firstBucket int, depth int) ([]int64, error) {
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	positions := make([]int64, 0, total)
+	buckets := make([][]int64, len(counts))
+	for i, n := range counts {
+		buckets[i] = positions[len(positions) : len(positions) : len(positions)+int(n)]
+		positions = positions[:len(positions)+int(n)]
+	}
+	for pos := int64(0); pos < digits.n; pos++ {
+		b := digits.bucket(pos) - firstBucket
+		if b >= 0 && b < len(buckets) {
+			buckets[b] = append(buckets[b], pos)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// The buckets are sorted in parallel.
+	wg := &sync.WaitGroup{}
+	for _, bucket := range buckets {
+		wg.Add(1)
+		go func(bucket []int64) {
+			defer wg.Done()
+			sort.Slice(bucket, func(i, j int) bool {
+				a, b := bucket[i], bucket[j]
+				c := digits.compare(a, b, depth)
+				if c != 0 {
+					return c < 0
+				}
+				return a < b
+			})
+		}(bucket)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+// OpenIndex opens the index file at the given path. The
+// digits are read from the given source, which has to hold
+// the digits the index was built from.
+func OpenIndex(filename string, cs piio.ChunkSource) (*Index, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := newIndex(file, cs)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return idx, nil
+}
+
+func newIndex(file *os.File, cs piio.ChunkSource) (*Index, error) {
+	header := make([]byte, indexHeaderSize)
+	_, err := io.ReadFull(file, header)
+	if err != nil || !bytes.Equal(header[:len(indexMagic)], indexMagic) {
+		return nil, &piio.UnsupportedFormatError{Reason: "not an index file"}
+	}
+	version := binary.BigEndian.Uint16(header[4:])
+	if version == 0 || version > IndexVersion {
+		return nil, &piio.UnsupportedFormatError{Reason: fmt.Sprintf("unsupported index version %d", version)}
+	}
+
+	idx := &Index{
+		file:       file,
+		cs:         cs,
+		width:      int(header[6]),
+		depth:      int(header[7]),
+		digitCount: int64(binary.BigEndian.Uint64(header[8:])),
+	}
+	if (idx.width != 4 && idx.width != 8) || idx.depth == 0 || idx.digitCount < 0 {
+		return nil, &piio.CorruptDataError{Index: -1, Reason: "invalid index header"}
+	}
+
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() != indexHeaderSize+idx.digitCount*int64(idx.width) {
+		return nil, &piio.CorruptDataError{Index: -1, Reason: fmt.Sprintf("expected %d positions in the index", idx.digitCount)}
+	}
+	avail, err := cs.AvailableDigits()
+	if err != nil {
+		return nil, err
+	}
+	if avail != idx.digitCount {
+		return nil, &piio.CorruptDataError{Index: -1, Reason: fmt.Sprintf("the index covers %d digits, but %d are available", idx.digitCount, avail)}
+	}
+	return idx, nil
+}
+
+// Depth returns the amount of leading digits by which the
+// suffixes are sorted. Longer sequences are found by
+// checking all occurrences of their first Depth digits.
+func (idx *Index) Depth() int {
+	return idx.depth
+}
+
+// Close closes the index file.
+func (idx *Index) Close() error {
+	return idx.file.Close()
+}
+
+// Search returns the occurrences of the sequence.
+func (idx *Index) Search(ctx context.Context, sequence []byte, offset, limit int) (*Result, error) {
+	err := checkSearch(sequence, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	key := sequence
+	if len(key) > idx.depth {
+		key = key[:idx.depth]
+	}
+	first, err := idx.bound(ctx, key, false)
+	if err != nil {
+		return nil, err
+	}
+	last, err := idx.bound(ctx, key, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if last-first > maxSortedPositions {
+		if len(sequence) > idx.depth {
+			return NewScanner(idx.cs).Search(ctx, sequence, offset, limit)
+		}
+		return idx.scanPage(ctx, sequence, last-first, offset, limit)
+	}
+
+	positions, err := idx.positions(first, last)
+	if err != nil {
+		return nil, err
+	}
+	if len(sequence) > idx.depth {
+		positions, err = idx.filter(ctx, positions, sequence)
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i] < positions[j] })
+
+	result := &Result{
+		FirstOccurrence: -1,
+		Count:           int64(len(positions)),
+		Positions:       []int64{},
+	}
+	if len(positions) > 0 {
+		result.FirstOccurrence = positions[0]
+	}
+	if offset < len(positions) {
+		end := len(positions)
+		if limit < end-offset {
+			end = offset + limit
+		}
+		result.Positions = positions[offset:end]
+	}
+	return result, nil
+}
+
+// First returns the position of the first occurrence of the
+// sequence. Frequent sequences are found by scanning the
+// digits up to their first occurrence.
+func (idx *Index) First(ctx context.Context, sequence []byte) (int64, error) {
+	err := checkSearch(sequence, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	key := sequence
+	if len(key) > idx.depth {
+		key = key[:idx.depth]
+	}
+	lower, err := idx.bound(ctx, key, false)
+	if err != nil {
+		return 0, err
+	}
+	upper, err := idx.bound(ctx, key, true)
+	if err != nil {
+		return 0, err
+	}
+	if upper-lower > maxSortedPositions {
+		return first(ctx, idx.cs, sequence)
+	}
+
+	positions, err := idx.positions(lower, upper)
+	if err != nil {
+		return 0, err
+	}
+	if len(sequence) > idx.depth {
+		positions, err = idx.filter(ctx, positions, sequence)
+		if err != nil {
+			return 0, err
+		}
+	}
+	pos := int64(-1)
+	for _, p := range positions {
+		if pos < 0 || p < pos {
+			pos = p
+		}
+	}
+	return pos, nil
+}
+
+// scanPage finds the first occurrence and the requested
+// page of a sequence known to occur count times by scanning
+// the digits from the start.
+func (idx *Index) scanPage(ctx context.Context, sequence []byte, count int64, offset, limit int) (*Result, error) {
+	result := &Result{
+		FirstOccurrence: -1,
+		Count:           count,
+		Positions:       []int64{},
+	}
+	end := int64(offset) + int64(limit)
+	if end > count {
+		end = count
+	}
+	if end < 1 {
+		end = 1
+	}
+
+	var n int64
+	err := scan(ctx, idx.cs, sequence, 0, func(pos int64) bool {
+		if n == 0 {
+			result.FirstOccurrence = pos
+		}
+		if n >= int64(offset) {
+			result.Positions = append(result.Positions, pos)
+		}
+		n++
+		return n < end
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// bound returns the index of the first suffix starting with
+// the key or, if upper is true, the index following the last
+// one.
+func (idx *Index) bound(ctx context.Context, key []byte, upper bool) (int64, error) {
+	lo, hi := int64(0), idx.digitCount
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		pos, err := idx.positionAt(mid)
+		if err != nil {
+			return 0, err
+		}
+		chnk, err := piio.GetChunkContext(ctx, idx.cs, pos, len(key))
+		if err != nil {
+			return 0, err
+		}
+		c := bytes.Compare(piio.AsUncompressedChunk(chnk).Digits, key)
+		if c < 0 || (upper && c == 0) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
+
+// positionAt returns the i-th position of the index.
+func (idx *Index) positionAt(i int64) (int64, error) {
+	positions, err := idx.positions(i, i+1)
+	if err != nil {
+		return 0, err
+	}
+	return positions[0], nil
+}
+
+// positions returns the positions from first up to, but
+// excluding, last in the order of the index.
+func (idx *Index) positions(first, last int64) ([]int64, error) {
+	buf := make([]byte, (last-first)*int64(idx.width))
+	_, err := idx.file.ReadAt(buf, indexHeaderSize+first*int64(idx.width))
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, &piio.CorruptDataError{Index: -1, Reason: "truncated index"}
+		}
+		return nil, err
+	}
+
+	positions := make([]int64, last-first)
+	for i := range positions {
+		if idx.width == 4 {
+			positions[i] = int64(binary.BigEndian.Uint32(buf[i*4:]))
+		} else {
+			positions[i] = int64(binary.BigEndian.Uint64(buf[i*8:]))
+		}
+	}
+	return positions, nil
+}
+
+// filter returns the positions at which the whole sequence
+// occurs.
+func (idx *Index) filter(ctx context.Context, positions []int64, sequence []byte) ([]int64, error) {
+	matches := positions[:0]
+	for _, pos := range positions {
+		chnk, err := piio.GetChunkContext(ctx, idx.cs, pos, len(sequence))
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(piio.AsUncompressedChunk(chnk).Digits, sequence) {
+			matches = append(matches, pos)
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,14 @@
+package search
+
+import (
+	"os"
+	"syscall"
+)
+
+func mapTable(file *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(file.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func unmapTable(mapping []byte) error {
+	return syscall.Munmap(mapping)
+}
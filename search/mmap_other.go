@@ -0,0 +1,23 @@
+//go:build !linux
+// +build !linux
+
+package search
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// mapTable reads the whole file into memory where memory
+// mapping is not supported.
+func mapTable(file *os.File, size int) ([]byte, error) {
+	_, err := file.Seek(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(file)
+}
+
+func unmapTable(mapping []byte) error {
+	return nil
+}
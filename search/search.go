@@ -0,0 +1,180 @@
+// Package search finds sequences of digits within the
+// digits of a ChunkSource, either by scanning all of them or
+// with the help of a suffix array index.
+package search
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/targodan/piio"
+)
+
+// MaximumSequenceLength is the length of the longest
+// sequence that can be searched for.
+const MaximumSequenceLength = 64
+
+// Result describes the occurrences of a sequence.
+type Result struct {
+	// FirstOccurrence is the index of the first digit of
+	// the first occurrence or -1 if there is none.
+	FirstOccurrence int64
+	// Count is the total amount of occurrences.
+	Count int64
+	// Positions are the indexes of the requested page of
+	// occurrences in ascending order.
+	Positions []int64
+}
+
+// Searcher finds the occurrences of a sequence of digits.
+type Searcher interface {
+	// Search returns the occurrences of the sequence, which
+	// consists of digit values between 0 and 9. Positions
+	// contains at most limit occurrences, skipping the first
+	// offset ones.
+	Search(ctx context.Context, sequence []byte, offset, limit int) (*Result, error)
+	// First returns the position of the first occurrence of
+	// the sequence or -1 if there is none. Unlike Search, it
+	// does not count all occurrences.
+	First(ctx context.Context, sequence []byte) (int64, error)
+}
+
+// ParseSequence converts a string of decimal digits to
+// the values of the digits.
+func ParseSequence(s string) ([]byte, error) {
+	sequence := make([]byte, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return nil, &piio.InvalidArgumentError{Reason: fmt.Sprintf("the sequence must only consist of decimal digits, got %q", s)}
+		}
+		sequence[i] = s[i] - '0'
+	}
+	return sequence, checkSearch(sequence, 0, 0)
+}
+
+func checkSearch(sequence []byte, offset, limit int) error {
+	if len(sequence) == 0 {
+		return &piio.InvalidArgumentError{Reason: "the sequence must not be empty"}
+	}
+	if len(sequence) > MaximumSequenceLength {
+		return &piio.InvalidArgumentError{Reason: fmt.Sprintf("the sequence must not be longer than %d digits", MaximumSequenceLength)}
+	}
+	if offset < 0 || limit < 0 {
+		return &piio.InvalidArgumentError{Reason: "the offset and limit must not be negative"}
+	}
+	return nil
+}
+
+// Scanner searches by reading all digits of a ChunkSource.
+// No index is needed, but every search takes linear time.
+type Scanner struct {
+	cs piio.ChunkSource
+}
+
+// NewScanner creates a new Scanner reading the digits of
+// the given source.
+func NewScanner(cs piio.ChunkSource) *Scanner {
+	return &Scanner{cs: cs}
+}
+
+// Search returns the occurrences of the sequence.
+func (s *Scanner) Search(ctx context.Context, sequence []byte, offset, limit int) (*Result, error) {
+	err := checkSearch(sequence, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{
+		FirstOccurrence: -1,
+		Positions:       []int64{},
+	}
+	err = scan(ctx, s.cs, sequence, 0, func(pos int64) bool {
+		if result.FirstOccurrence < 0 {
+			result.FirstOccurrence = pos
+		}
+		if result.Count >= int64(offset) && result.Count < int64(offset)+int64(limit) {
+			result.Positions = append(result.Positions, pos)
+		}
+		result.Count++
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// First returns the position of the first occurrence of the
+// sequence, reading the digits only up to it.
+func (s *Scanner) First(ctx context.Context, sequence []byte) (int64, error) {
+	err := checkSearch(sequence, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	return first(ctx, s.cs, sequence)
+}
+
+// first returns the position of the first occurrence of the
+// sequence in the source or -1 if there is none.
+func first(ctx context.Context, cs piio.ChunkSource, sequence []byte) (int64, error) {
+	pos := int64(-1)
+	err := scan(ctx, cs, sequence, 0, func(p int64) bool {
+		pos = p
+		return false
+	})
+	if err != nil {
+		return 0, err
+	}
+	return pos, nil
+}
+
+// scan calls found with the position of every occurrence of
+// the sequence starting at or after firstIndex in ascending
+// order until it returns false.
+func scan(ctx context.Context, cs piio.ChunkSource, sequence []byte, firstIndex int64, found func(pos int64) bool) error {
+	blockSize := cs.MaximumChunkSize()
+	if blockSize < len(sequence) {
+		return &piio.InvalidArgumentError{Reason: "the sequence is longer than the maximum chunk size"}
+	}
+	avail, err := cs.AvailableDigits()
+	if err != nil {
+		return err
+	}
+
+	// The last digits of the previous block are kept, so
+	// occurrences spanning two blocks are found.
+	buf := make([]byte, 0, blockSize+len(sequence)-1)
+	bufStart := firstIndex
+	for pos := firstIndex; pos < avail; {
+		chnk, err := piio.GetChunkContext(ctx, cs, pos, blockSize)
+		if errors.Is(err, piio.ErrOutOfRange) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		digits := piio.AsUncompressedChunk(chnk).Digits
+		if len(digits) == 0 {
+			break
+		}
+		buf = append(buf, digits...)
+		pos += int64(len(digits))
+
+		for i := 0; i+len(sequence) <= len(buf); i++ {
+			if buf[i] == sequence[0] && bytes.Equal(buf[i:i+len(sequence)], sequence) {
+				if !found(bufStart + int64(i)) {
+					return nil
+				}
+			}
+		}
+
+		keep := len(sequence) - 1
+		if keep > len(buf) {
+			keep = len(buf)
+		}
+		bufStart += int64(len(buf) - keep)
+		buf = append(buf[:0], buf[len(buf)-keep:]...)
+	}
+	return nil
+}
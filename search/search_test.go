@@ -0,0 +1,150 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/targodan/piio"
+	"github.com/targodan/piio/chudnovsky"
+	"github.com/targodan/piio/internal/piiotest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// occurrences returns the positions of all occurrences of
+// the sequence of ASCII digits in the text.
+func occurrences(text, sequence string) []int64 {
+	positions := []int64{}
+	for i := 0; i+len(sequence) <= len(text); i++ {
+		if strings.HasPrefix(text[i:], sequence) {
+			positions = append(positions, int64(i))
+		}
+	}
+	return positions
+}
+
+func TestSearch(t *testing.T) {
+	text, err := chudnovsky.Pi(context.Background(), 5000, chudnovsky.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	digits := make([]byte, len(text))
+	for i, c := range text {
+		digits[i] = c - '0'
+	}
+	cs := &piiotest.MemoryChunkSource{Digits: digits, MaxSize: 100}
+
+	file, err := ioutil.TempFile("", "piio-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	err = BuildIndex(context.Background(), file, cs, 4)
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := OpenIndex(file.Name(), cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+
+	searchers := map[string]Searcher{
+		"a Scanner": NewScanner(cs),
+		"an Index":  index,
+	}
+	for name, searcher := range searchers {
+		Convey("Given "+name, t, func() {
+			for _, s := range []string{"3", "14159", "999999", "26", "1415926535", "0000000", "58209"} {
+				expected := occurrences(string(text), s)
+				sequence, err := ParseSequence(s)
+				So(err, ShouldBeNil)
+
+				result, err := searcher.Search(context.Background(), sequence, 0, 1000)
+				So(err, ShouldBeNil)
+				So(result.Count, ShouldEqual, len(expected))
+				So(result.Positions, ShouldResemble, expected)
+				first, err := searcher.First(context.Background(), sequence)
+				So(err, ShouldBeNil)
+				if len(expected) > 0 {
+					So(result.FirstOccurrence, ShouldEqual, expected[0])
+					So(first, ShouldEqual, expected[0])
+				} else {
+					So(result.FirstOccurrence, ShouldEqual, -1)
+					So(first, ShouldEqual, -1)
+				}
+			}
+
+			Convey("pages should be returned.", func() {
+				expected := occurrences(string(text), "26")
+				result, err := searcher.Search(context.Background(), []byte{2, 6}, 3, 5)
+				So(err, ShouldBeNil)
+				So(result.Positions, ShouldResemble, expected[3:8])
+				So(result.FirstOccurrence, ShouldEqual, expected[0])
+
+				result, err = searcher.Search(context.Background(), []byte{2, 6}, len(expected), 5)
+				So(err, ShouldBeNil)
+				So(result.Positions, ShouldBeEmpty)
+			})
+		})
+	}
+
+	Convey("Given a first occurrence table", t, func() {
+		file, err := ioutil.TempFile("", "piio-first")
+		So(err, ShouldBeNil)
+		defer os.Remove(file.Name())
+		err = BuildFirstOccurrenceTable(context.Background(), file, cs, 3)
+		file.Close()
+		So(err, ShouldBeNil)
+
+		table, err := OpenFirstOccurrenceTable(file.Name())
+		So(err, ShouldBeNil)
+		defer table.Close()
+		So(table.Length(), ShouldEqual, 3)
+		So(table.DigitCount(), ShouldEqual, len(digits))
+
+		Convey("all sequences should be found at their first occurrence.", func() {
+			for _, s := range []string{"314", "141", "000", "999", "265", "123"} {
+				expected := occurrences(string(text), s)
+				sequence, err := ParseSequence(s)
+				So(err, ShouldBeNil)
+				pos, err := table.Lookup(sequence)
+				So(err, ShouldBeNil)
+				if len(expected) > 0 {
+					So(pos, ShouldEqual, expected[0])
+				} else {
+					So(pos, ShouldEqual, -1)
+				}
+			}
+		})
+		Convey("sequences of other lengths should be rejected.", func() {
+			_, err := table.Lookup([]byte{1, 4})
+			So(errors.Is(err, piio.ErrInvalidArgument), ShouldBeTrue)
+		})
+	})
+
+	Convey("Parsing invalid sequences should fail.", t, func() {
+		for _, s := range []string{"", "12a", strings.Repeat("1", MaximumSequenceLength+1)} {
+			_, err := ParseSequence(s)
+			So(errors.Is(err, piio.ErrInvalidArgument), ShouldBeTrue)
+		}
+	})
+	Convey("Building an index in small passes should give the same index.", t, func() {
+		expected, err := ioutil.ReadFile(file.Name())
+		So(err, ShouldBeNil)
+		buf := &bytes.Buffer{}
+		err = buildIndex(context.Background(), buf, cs, 4, 7)
+		So(err, ShouldBeNil)
+		So(buf.Bytes(), ShouldResemble, expected)
+	})
+	Convey("Opening an index for other digits should fail.", t, func() {
+		_, err := OpenIndex(file.Name(), &piiotest.MemoryChunkSource{Digits: digits[:100], MaxSize: 100})
+		So(errors.Is(err, piio.ErrCorruptData), ShouldBeTrue)
+	})
+}
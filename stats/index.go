@@ -0,0 +1,202 @@
+package stats
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/targodan/piio"
+)
+
+// IndexVersion is the version of the index files written
+// by this package.
+const IndexVersion = 1
+
+// DefaultIndexInterval is the default amount of digits
+// between two entries of an index.
+const DefaultIndexInterval = 1 << 20
+
+var indexMagic = []byte("PIIC")
+
+// indexHeaderSize is the size of the header of an index.
+const indexHeaderSize = 4 + 2 + 8 + 8
+
+// entrySize is the size of an entry of an index.
+const entrySize = 10 * 8
+
+// Index is a Counter using the cumulative counts of the
+// digits every interval digits, so only the digits between
+// the ends of the range and the closest entries are read.
+//
+// The binary layout of the file is as follows, all numbers
+// are big endian.
+//
+//	4 bytes  magic "PIIC"
+//	uint16   version
+//	uint64   interval
+//	uint64   amount of digits
+//	entries  10 uint64 counts of the digits in front of
+//	         every multiple of the interval
+type Index struct {
+	cs         piio.ChunkSource
+	interval   int64
+	digitCount int64
+	entries    []Counts
+}
+
+// BuildIndex writes an index of the digits of the source to
+// w with an entry every interval digits. The digits are read
+// in a single pass.
+func BuildIndex(ctx context.Context, w io.Writer, cs piio.ChunkSource, interval int64) error {
+	if interval <= 0 {
+		return &piio.InvalidArgumentError{Reason: "the interval must be positive"}
+	}
+	avail, err := cs.AvailableDigits()
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, indexHeaderSize)
+	copy(header, indexMagic)
+	binary.BigEndian.PutUint16(header[4:], IndexVersion)
+	binary.BigEndian.PutUint64(header[6:], uint64(interval))
+	binary.BigEndian.PutUint64(header[14:], uint64(avail))
+
+	bw := bufio.NewWriter(w)
+	_, err = bw.Write(header)
+	if err != nil {
+		return err
+	}
+
+	var counts Counts
+	entry := make([]byte, entrySize)
+	for pos := int64(0); pos+interval <= avail; pos += interval {
+		c, err := count(ctx, cs, pos, interval)
+		if err != nil {
+			return err
+		}
+		for d := range counts {
+			counts[d] += c[d]
+			binary.BigEndian.PutUint64(entry[d*8:], uint64(counts[d]))
+		}
+		_, err = bw.Write(entry)
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// OpenIndex reads the index file at the given path. The
+// digits are read from the given source, which has to hold
+// the digits the index was built from. The whole file is
+// read into memory, so unlike the search index the returned
+// Index holds no open file and does not need to be closed.
+func OpenIndex(filename string, cs piio.ChunkSource) (*Index, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < indexHeaderSize || !bytes.Equal(data[:len(indexMagic)], indexMagic) {
+		return nil, &piio.UnsupportedFormatError{Reason: "not a statistics index file"}
+	}
+	version := binary.BigEndian.Uint16(data[4:])
+	if version == 0 || version > IndexVersion {
+		return nil, &piio.UnsupportedFormatError{Reason: fmt.Sprintf("unsupported statistics index version %d", version)}
+	}
+
+	idx := &Index{
+		cs:         cs,
+		interval:   int64(binary.BigEndian.Uint64(data[6:])),
+		digitCount: int64(binary.BigEndian.Uint64(data[14:])),
+	}
+	if idx.interval <= 0 || idx.digitCount < 0 {
+		return nil, &piio.CorruptDataError{Index: -1, Reason: "invalid statistics index header"}
+	}
+	entries := idx.digitCount / idx.interval
+	data = data[indexHeaderSize:]
+	if int64(len(data)) != entries*entrySize {
+		return nil, &piio.CorruptDataError{Index: -1, Reason: fmt.Sprintf("expected %d entries in the statistics index", entries)}
+	}
+
+	avail, err := cs.AvailableDigits()
+	if err != nil {
+		return nil, err
+	}
+	if avail != idx.digitCount {
+		return nil, &piio.CorruptDataError{Index: -1, Reason: fmt.Sprintf("the index covers %d digits, but %d are available", idx.digitCount, avail)}
+	}
+
+	idx.entries = make([]Counts, entries)
+	for i := range idx.entries {
+		for d := range idx.entries[i] {
+			idx.entries[i][d] = int64(binary.BigEndian.Uint64(data[i*entrySize+d*8:]))
+		}
+	}
+	return idx, nil
+}
+
+// Interval returns the amount of digits between two
+// entries of the index.
+func (idx *Index) Interval() int64 {
+	return idx.interval
+}
+
+// Count returns the counts of the digits of the range.
+func (idx *Index) Count(ctx context.Context, firstIndex int64, size int64) (Counts, error) {
+	err := checkRange(idx.cs, firstIndex, size)
+	if err != nil {
+		return Counts{}, err
+	}
+	end := firstIndex + size
+	if firstIndex/idx.interval == end/idx.interval {
+		return count(ctx, idx.cs, firstIndex, size)
+	}
+
+	counts, err := idx.countInFront(ctx, end)
+	if err != nil {
+		return Counts{}, err
+	}
+	head, err := idx.countInFront(ctx, firstIndex)
+	if err != nil {
+		return Counts{}, err
+	}
+	counts.Sub(&head)
+	return counts, nil
+}
+
+// countInFront returns the counts of the digits in front of
+// the given index, starting from the closest entry.
+func (idx *Index) countInFront(ctx context.Context, index int64) (Counts, error) {
+	block := index / idx.interval
+	offset := index % idx.interval
+
+	// Counting back from the next entry reads fewer digits
+	// if the index is in the second half of a block.
+	if offset > idx.interval/2 && block < int64(len(idx.entries)) {
+		counts := idx.entries[block]
+		tail, err := count(ctx, idx.cs, index, idx.interval-offset)
+		if err != nil {
+			return Counts{}, err
+		}
+		counts.Sub(&tail)
+		return counts, nil
+	}
+
+	var counts Counts
+	if block > 0 {
+		counts = idx.entries[block-1]
+	}
+	head, err := count(ctx, idx.cs, index-offset, offset)
+	if err != nil {
+		return Counts{}, err
+	}
+	for d := range counts {
+		counts[d] += head[d]
+	}
+	return counts, nil
+}
@@ -0,0 +1,156 @@
+// Package stats computes statistics of the frequencies of
+// the digits within ranges of a ChunkSource, either by
+// counting all of them or with the help of an index of
+// cumulative counts.
+package stats
+
+import (
+	"context"
+
+	"github.com/targodan/piio"
+)
+
+// Counts holds the amount of occurrences of every digit.
+type Counts [10]int64
+
+// Total returns the amount of digits counted.
+func (c *Counts) Total() int64 {
+	var total int64
+	for _, n := range c {
+		total += n
+	}
+	return total
+}
+
+// Add adds the digits to the counts.
+func (c *Counts) Add(digits []byte) {
+	for _, d := range digits {
+		c[d]++
+	}
+}
+
+// Sub subtracts the other counts.
+func (c *Counts) Sub(other *Counts) {
+	for d := range c {
+		c[d] -= other[d]
+	}
+}
+
+// Stats describes the frequencies of the digits of a range.
+type Stats struct {
+	Range  piio.Range
+	Counts Counts
+	// Mean is the mean value of the digits.
+	Mean float64
+	// ChiSquare is the chi-square statistic of the counts
+	// compared with a uniform distribution of the digits.
+	// It has 9 degrees of freedom.
+	ChiSquare float64
+}
+
+// NewStats computes the statistics of the given counts of
+// the digits of a range.
+func NewStats(r piio.Range, counts Counts) *Stats {
+	s := &Stats{
+		Range:  r,
+		Counts: counts,
+	}
+	total := counts.Total()
+	if total == 0 {
+		return s
+	}
+
+	expected := float64(total) / 10
+	var sum int64
+	for d, n := range counts {
+		sum += int64(d) * n
+		diff := float64(n) - expected
+		s.ChiSquare += diff * diff / expected
+	}
+	s.Mean = float64(sum) / float64(total)
+	return s
+}
+
+// Counter counts the digits within a range.
+type Counter interface {
+	Count(ctx context.Context, firstIndex int64, size int64) (Counts, error)
+}
+
+// Compute returns the statistics of the given range using
+// the counter.
+func Compute(ctx context.Context, counter Counter, firstIndex int64, size int64) (*Stats, error) {
+	counts, err := counter.Count(ctx, firstIndex, size)
+	if err != nil {
+		return nil, err
+	}
+	return NewStats(piio.Range{FirstIndex: firstIndex, Size: size}, counts), nil
+}
+
+// Scanner counts by reading all digits of the range from a
+// ChunkSource.
+type Scanner struct {
+	cs piio.ChunkSource
+}
+
+// NewScanner creates a new Scanner reading the digits of
+// the given source.
+func NewScanner(cs piio.ChunkSource) *Scanner {
+	return &Scanner{cs: cs}
+}
+
+// Count returns the counts of the digits of the range.
+func (s *Scanner) Count(ctx context.Context, firstIndex int64, size int64) (Counts, error) {
+	err := checkRange(s.cs, firstIndex, size)
+	if err != nil {
+		return Counts{}, err
+	}
+	return count(ctx, s.cs, firstIndex, size)
+}
+
+// checkRange checks whether the range is available from
+// the source.
+func checkRange(cs piio.ChunkSource, firstIndex int64, size int64) error {
+	if firstIndex < 0 || size < 0 {
+		return &piio.InvalidArgumentError{Reason: "the first index and size must not be negative"}
+	}
+	avail, err := cs.AvailableDigits()
+	if err != nil {
+		return err
+	}
+	if firstIndex+size > avail {
+		return &piio.OutOfRangeError{
+			Requested: piio.Range{FirstIndex: firstIndex, Size: size},
+			Available: piio.Range{FirstIndex: 0, Size: avail},
+		}
+	}
+	return nil
+}
+
+// count counts the digits of the range, which has to be
+// available from the source.
+func count(ctx context.Context, cs piio.ChunkSource, firstIndex int64, size int64) (Counts, error) {
+	var counts Counts
+	blockSize := int64(cs.MaximumChunkSize())
+	for pos, end := firstIndex, firstIndex+size; pos < end; {
+		n := end - pos
+		if n > blockSize {
+			n = blockSize
+		}
+		chnk, err := piio.GetChunkContext(ctx, cs, pos, int(n))
+		if err != nil {
+			return Counts{}, err
+		}
+		digits := piio.AsUncompressedChunk(chnk).Digits
+		if len(digits) == 0 {
+			return Counts{}, &piio.CorruptDataError{Index: pos, Reason: "fewer digits than available"}
+		}
+		for i, d := range digits {
+			if d > 9 {
+				return Counts{}, &piio.CorruptDataError{Index: pos + int64(i), Reason: "invalid digit"}
+			}
+		}
+		counts.Add(digits)
+		pos += int64(len(digits))
+	}
+	return counts, nil
+}
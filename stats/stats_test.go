@@ -0,0 +1,87 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/targodan/piio"
+	"github.com/targodan/piio/chudnovsky"
+	"github.com/targodan/piio/internal/piiotest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStats(t *testing.T) {
+	Convey("Computing the statistics of uniform counts", t, func() {
+		s := NewStats(piio.Range{FirstIndex: 0, Size: 20}, Counts{2, 2, 2, 2, 2, 2, 2, 2, 2, 2})
+
+		Convey("should yield the expected values.", func() {
+			So(s.Counts.Total(), ShouldEqual, 20)
+			So(s.Mean, ShouldAlmostEqual, 4.5)
+			So(s.ChiSquare, ShouldAlmostEqual, 0)
+		})
+	})
+	Convey("Computing the statistics of skewed counts", t, func() {
+		s := NewStats(piio.Range{FirstIndex: 0, Size: 10}, Counts{10})
+
+		Convey("should yield the expected values.", func() {
+			So(s.Mean, ShouldAlmostEqual, 0)
+			So(s.ChiSquare, ShouldAlmostEqual, 90)
+		})
+	})
+}
+
+func TestIndex(t *testing.T) {
+	text, err := chudnovsky.Pi(context.Background(), 5000, chudnovsky.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	digits := make([]byte, len(text))
+	for i, c := range text {
+		digits[i] = c - '0'
+	}
+	cs := &piiotest.MemoryChunkSource{Digits: digits, MaxSize: 64}
+
+	file, err := ioutil.TempFile("", "piio-stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	err = BuildIndex(context.Background(), file, cs, 300)
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Convey("Given a statistics index", t, func() {
+		index, err := OpenIndex(file.Name(), cs)
+		So(err, ShouldBeNil)
+		So(index.Interval(), ShouldEqual, 300)
+
+		Convey("the counts should match the digits.", func() {
+			for _, r := range [][2]int64{{0, 5000}, {0, 1}, {17, 100}, {250, 100}, {299, 2}, {300, 300}, {1, 4999}, {1234, 2345}, {4990, 10}, {100, 0}} {
+				var expected Counts
+				expected.Add(digits[r[0] : r[0]+r[1]])
+
+				counts, err := index.Count(context.Background(), r[0], r[1])
+				So(err, ShouldBeNil)
+				So(counts, ShouldResemble, expected)
+
+				counts, err = NewScanner(cs).Count(context.Background(), r[0], r[1])
+				So(err, ShouldBeNil)
+				So(counts, ShouldResemble, expected)
+			}
+		})
+		Convey("ranges past the end should fail.", func() {
+			_, err := index.Count(context.Background(), 4990, 11)
+			So(errors.Is(err, piio.ErrOutOfRange), ShouldBeTrue)
+		})
+		Convey("opening it for other digits should fail.", func() {
+			_, err := OpenIndex(file.Name(), &piiotest.MemoryChunkSource{Digits: digits[:4000], MaxSize: 64})
+			So(errors.Is(err, piio.ErrCorruptData), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,342 @@
+package piio
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// streamBlockDigits is the amount of digits packed at once
+	// by a CompressWriter. It is a multiple of DenseBlockDigits,
+	// so all chunks but the last can be appended to a file.
+	streamBlockDigits = 4096 * DenseBlockDigits
+	// streamBlockBytes is the amount of bytes read at once by
+	// a DecompressReader. It is a multiple of DenseBlockBytes,
+	// so no block is split across reads.
+	streamBlockBytes = 4096 * DenseBlockBytes
+)
+
+var errCompressWriterClosed = errors.New("the CompressWriter is closed")
+
+// isTextFormatting returns true for the characters that may
+// appear between the digits of a text file, which are the
+// decimal point and whitespace.
+func isTextFormatting(b byte) bool {
+	switch b {
+	case '.', ' ', '\t', '\n', '\r':
+		return true
+	}
+	return false
+}
+
+// CompressWriter is an io.Writer packing text digits into
+// another file format. Decimal points and whitespace are
+// skipped, so the input may be formatted arbitrarily, e.g.
+// "3.14159 26535\n89793". Any other character is an error.
+// The input is never seeked, so it can be piped from any
+// source.
+type CompressWriter struct {
+	writeChunk func(Chunk) error
+	close      func() error
+	digits     []byte
+	written    int64
+	err        error
+}
+
+// NewCompressWriter creates a new CompressWriter writing
+// the digits in the given file format to w without a
+// header.
+func NewCompressWriter(w io.Writer, format FileFormat) (*CompressWriter, error) {
+	_, err := (&Header{Format: format}).DataSize()
+	if err != nil {
+		return nil, err
+	}
+	return newCompressWriter(func(chnk Chunk) error {
+		return WriteChunk(chnk, format, w)
+	}, nil), nil
+}
+
+// NewFileCompressWriter creates a new CompressWriter
+// writing the digits to the given FileWriter. Closing the
+// CompressWriter also closes the FileWriter.
+func NewFileCompressWriter(fw *FileWriter) *CompressWriter {
+	return newCompressWriter(fw.WriteChunk, fw.Close)
+}
+
+func newCompressWriter(writeChunk func(Chunk) error, close func() error) *CompressWriter {
+	return &CompressWriter{
+		writeChunk: writeChunk,
+		close:      close,
+		digits:     make([]byte, 0, streamBlockDigits),
+	}
+}
+
+// Write packs the digits in p. Digits are buffered until
+// a complete block is available or the writer is closed.
+func (cw *CompressWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	for i, b := range p {
+		if '0' <= b && b <= '9' {
+			cw.digits = append(cw.digits, b-'0')
+			if len(cw.digits) == cap(cw.digits) {
+				cw.err = cw.flush()
+				if cw.err != nil {
+					return i + 1, cw.err
+				}
+			}
+			continue
+		}
+		if !isTextFormatting(b) {
+			cw.err = &InvalidArgumentError{fmt.Sprintf("unexpected character %q after %d digits", b, cw.Digits())}
+			return i, cw.err
+		}
+	}
+	return len(p), nil
+}
+
+func (cw *CompressWriter) flush() error {
+	if len(cw.digits) == 0 {
+		return nil
+	}
+	err := cw.writeChunk(&UncompressedChunk{
+		FirstDigitIndex: cw.written,
+		Digits:          cw.digits,
+	})
+	if err != nil {
+		return err
+	}
+	cw.written += int64(len(cw.digits))
+	cw.digits = cw.digits[:0]
+	return nil
+}
+
+// Digits returns the amount of digits written so far.
+func (cw *CompressWriter) Digits() int64 {
+	return cw.written + int64(len(cw.digits))
+}
+
+// Close writes the remaining digits. It does not close the
+// underlying writer.
+func (cw *CompressWriter) Close() error {
+	if cw.err != nil {
+		return cw.err
+	}
+	cw.err = cw.flush()
+	if cw.err != nil {
+		return cw.err
+	}
+	if cw.close != nil {
+		cw.err = cw.close()
+		if cw.err != nil {
+			return cw.err
+		}
+	}
+	cw.err = errCompressWriterClosed
+	return nil
+}
+
+// DecompressReader is an io.Reader emitting the digits of
+// a file as text, one character per digit without any
+// formatting. The input is never seeked, so it can be piped
+// from any source.
+type DecompressReader struct {
+	r      *bufio.Reader
+	format FileFormat
+	// remaining is the amount of digits left according to
+	// the header or -1 if there is no header.
+	remaining int64
+	index     int64
+	raw       []byte
+	out       []byte
+	err       error
+}
+
+// NewDecompressReader creates a new DecompressReader reading
+// digits in the given file format from r. If the input starts
+// with a Header, the file format and amount of digits stated
+// in it are used instead.
+func NewDecompressReader(r io.Reader, format FileFormat) (*DecompressReader, error) {
+	dr := &DecompressReader{
+		r:         bufio.NewReader(r),
+		format:    format,
+		remaining: -1,
+		raw:       make([]byte, streamBlockBytes),
+	}
+
+	magic, _ := dr.r.Peek(len(headerMagic))
+	if bytes.Equal(magic, headerMagic) {
+		h, err := ReadHeader(dr.r)
+		if err != nil {
+			return nil, err
+		}
+		_, err = dr.r.Discard(int(h.DataOffset) - headerFixedSize - len(h.Constant))
+		if err != nil {
+			return nil, &CorruptDataError{-1, "truncated header"}
+		}
+		dr.format = h.Format
+		dr.remaining = h.DigitCount
+	}
+
+	_, err := (&Header{Format: dr.format}).DataSize()
+	if err != nil {
+		return nil, err
+	}
+	return dr, nil
+}
+
+// Read reads the text digits into p.
+func (dr *DecompressReader) Read(p []byte) (int, error) {
+	for len(dr.out) == 0 {
+		if dr.err != nil {
+			return 0, dr.err
+		}
+		dr.fill()
+	}
+	n := copy(p, dr.out)
+	dr.out = dr.out[n:]
+	return n, nil
+}
+
+// fill decodes the next block of the input.
+func (dr *DecompressReader) fill() {
+	n, err := io.ReadFull(dr.r, dr.raw)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	data := dr.raw[:n]
+	out := dr.out[:0]
+
+	// Invalid data is only reported once the valid digits in
+	// front of it have been read.
+	var dataErr error
+	if dr.format == FileFormatText {
+		for _, b := range data {
+			if '0' <= b && b <= '9' {
+				out = append(out, b)
+			} else if !isTextFormatting(b) {
+				dataErr = &CorruptDataError{dr.index + int64(len(out)), fmt.Sprintf("unexpected character %q", b)}
+				break
+			}
+		}
+	} else {
+		var chnk Chunk
+		if dr.format == FileFormatCompressed {
+			chnk = compressedChunkAt(data, dr.index, len(data)*2)
+		} else {
+			chnk = denseChunkAt(data, dr.index, len(data)*8)
+		}
+		for i := chnk.FirstIndex(); i <= chnk.LastIndex(); i++ {
+			d, err := chnk.Digit(i)
+			if err != nil {
+				dataErr = err
+				break
+			}
+			out = append(out, '0'+d)
+		}
+	}
+	if dataErr != nil {
+		err = dataErr
+	}
+
+	if dr.remaining >= 0 {
+		if int64(len(out)) >= dr.remaining {
+			// Anything following the digits is padding.
+			out = out[:dr.remaining]
+			err = io.EOF
+		}
+		dr.remaining -= int64(len(out))
+		if err == io.EOF && dr.remaining > 0 {
+			err = &CorruptDataError{-1, fmt.Sprintf("data ends %d digits before the end stated in the header", dr.remaining)}
+		}
+	}
+	dr.index += int64(len(out))
+	dr.out = out
+	dr.err = err
+}
+
+// DigitCount returns the amount of digits stated in the
+// header of the input or -1 if it has no header.
+func (dr *DecompressReader) DigitCount() int64 {
+	if dr.remaining < 0 {
+		return -1
+	}
+	return dr.index + dr.remaining
+}
+
+// ChunkSourceReader is an io.Reader emitting the digits of
+// a ChunkSource as text, one character per digit. The
+// digits are requested in chunks of the maximum chunk size
+// of the source.
+type ChunkSourceReader struct {
+	source ChunkSource
+	index  int64
+	// remaining is the amount of digits left to read or -1
+	// if all digits up to the end of the source are read.
+	remaining int64
+	out       []byte
+}
+
+// NewChunkSourceReader creates a new ChunkSourceReader
+// reading length digits starting at firstIndex. If length
+// is negative, all digits up to the end of the source are
+// read.
+func NewChunkSourceReader(cs ChunkSource, firstIndex, length int64) *ChunkSourceReader {
+	if length < 0 {
+		length = -1
+	}
+	return &ChunkSourceReader{
+		source:    cs,
+		index:     firstIndex,
+		remaining: length,
+	}
+}
+
+// Read reads the text digits into p. Requesting digits
+// beyond the end of the source returns an OutOfRangeError
+// unless the length was negative.
+func (r *ChunkSourceReader) Read(p []byte) (int, error) {
+	if len(r.out) == 0 {
+		err := r.fill()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+func (r *ChunkSourceReader) fill() error {
+	if r.remaining == 0 {
+		return io.EOF
+	}
+	size := r.source.MaximumChunkSize()
+	if r.remaining > 0 && r.remaining < int64(size) {
+		size = int(r.remaining)
+	}
+
+	chnk, err := r.source.GetChunk(r.index, size)
+	if r.remaining < 0 && errors.Is(err, ErrOutOfRange) {
+		return io.EOF
+	}
+	if err != nil {
+		return err
+	}
+
+	c := AsUncompressedChunk(chnk)
+	out := r.out[:0]
+	for _, d := range c.Digits {
+		out = append(out, '0'+d)
+	}
+	r.out = out
+	r.index += int64(len(out))
+	if r.remaining > 0 {
+		r.remaining -= int64(len(out))
+	}
+	return nil
+}
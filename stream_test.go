@@ -0,0 +1,124 @@
+package piio
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCompressWriter(t *testing.T) {
+	Convey("Given formatted text digits", t, func() {
+		text := "3.14159 26535\n9"
+
+		Convey("compressing them should skip the formatting.", func() {
+			buf := &bytes.Buffer{}
+			cw, err := NewCompressWriter(buf, FileFormatCompressed)
+			So(err, ShouldBeNil)
+			_, err = cw.Write([]byte(text))
+			So(err, ShouldBeNil)
+			So(cw.Close(), ShouldBeNil)
+			So(cw.Digits(), ShouldEqual, 12)
+			So(buf.Bytes(), ShouldResemble, compressedPi)
+		})
+		Convey("packing them densely should work.", func() {
+			buf := &bytes.Buffer{}
+			cw, err := NewCompressWriter(buf, FileFormatDense)
+			So(err, ShouldBeNil)
+			_, err = cw.Write([]byte(text))
+			So(err, ShouldBeNil)
+			So(cw.Close(), ShouldBeNil)
+			So(buf.Bytes(), ShouldResemble, densePi)
+		})
+	})
+	Convey("Given invalid text", t, func() {
+		cw, err := NewCompressWriter(&bytes.Buffer{}, FileFormatCompressed)
+		So(err, ShouldBeNil)
+
+		Convey("writing it should fail.", func() {
+			n, err := cw.Write([]byte("3.14x"))
+			So(errors.Is(err, ErrInvalidArgument), ShouldBeTrue)
+			So(n, ShouldEqual, 4)
+		})
+	})
+}
+
+func TestDecompressReader(t *testing.T) {
+	Convey("Given more digits than fit into one block", t, func() {
+		digits := make([]byte, streamBlockDigits*2+7)
+		for i := range digits {
+			digits[i] = '0' + byte(rand.Intn(10))
+		}
+
+		for _, format := range []FileFormat{FileFormatCompressed, FileFormatDense, FileFormatText} {
+			format := format
+			Convey("writing and reading them as "+format.String()+" should work.", func() {
+				buf := &bytes.Buffer{}
+				cw, err := NewCompressWriter(buf, format)
+				So(err, ShouldBeNil)
+				_, err = cw.Write(digits)
+				So(err, ShouldBeNil)
+				So(cw.Close(), ShouldBeNil)
+
+				dr, err := NewDecompressReader(buf, format)
+				So(err, ShouldBeNil)
+				read, err := ioutil.ReadAll(dr)
+				So(err, ShouldBeNil)
+				// Without a header the padding cannot be told apart
+				// from the digits.
+				So(len(read), ShouldBeGreaterThanOrEqualTo, len(digits))
+				So(read[:len(digits)], ShouldResemble, digits)
+			})
+		}
+	})
+	Convey("Given a dense file with a header", t, func() {
+		file, err := ioutil.TempFile("", "piio")
+		So(err, ShouldBeNil)
+		defer os.Remove(file.Name())
+		defer file.Close()
+
+		fw, err := NewFileWriter(file, FileFormatDense)
+		So(err, ShouldBeNil)
+		cw := NewFileCompressWriter(fw)
+		_, err = cw.Write([]byte("3.1415926"))
+		So(err, ShouldBeNil)
+		So(cw.Close(), ShouldBeNil)
+
+		Convey("reading it should use the header.", func() {
+			_, err := file.Seek(0, 0)
+			So(err, ShouldBeNil)
+			dr, err := NewDecompressReader(file, FileFormatCompressed)
+			So(err, ShouldBeNil)
+			read, err := ioutil.ReadAll(dr)
+			So(err, ShouldBeNil)
+			So(string(read), ShouldEqual, "31415926")
+		})
+	})
+	Convey("Given a compressed file with an invalid digit", t, func() {
+		dr, err := NewDecompressReader(bytes.NewReader([]byte{0x31, 0x4F}), FileFormatCompressed)
+		So(err, ShouldBeNil)
+
+		Convey("the valid digits should be read before the error.", func() {
+			read, err := ioutil.ReadAll(dr)
+			So(string(read), ShouldEqual, "314")
+			var dataErr *CorruptDataError
+			So(errors.As(err, &dataErr), ShouldBeTrue)
+			So(dataErr.Index, ShouldEqual, 3)
+		})
+	})
+	Convey("Given text input", t, func() {
+		dr, err := NewDecompressReader(strings.NewReader("3.1415\n9265"), FileFormatText)
+		So(err, ShouldBeNil)
+
+		Convey("the formatting should be removed.", func() {
+			read, err := ioutil.ReadAll(dr)
+			So(err, ShouldBeNil)
+			So(string(read), ShouldEqual, "314159265")
+		})
+	})
+}
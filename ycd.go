@@ -0,0 +1,274 @@
+package piio
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	ycdExtension     = ".ycd"
+	ycdEndHeader     = "EndHeader"
+	ycdWordSize      = 8
+	ycdDigitsPerWord = 19
+	ycdMaxWord       = 10000000000000000000
+)
+
+// ycdHeader holds the parts of a .ycd file header
+// that are needed to locate digits.
+type ycdHeader struct {
+	base        int
+	firstDigits string
+	totalDigits int64
+	blockSize   int64
+	blockID     int64
+	dataOffset  int64
+}
+
+// readYCDHeader parses the text header of a .ycd file.
+// The header consists of "Key:\tValue" lines and is
+// terminated by an "EndHeader" line. The digit data
+// starts right after the first null byte following it.
+func readYCDHeader(input io.Reader) (*ycdHeader, error) {
+	r := bufio.NewReader(input)
+	h := &ycdHeader{
+		blockID: -1,
+	}
+
+	var err error
+	for {
+		var line string
+		line, err = r.ReadString('\n')
+		if err != nil {
+			return nil, &CorruptDataError{-1, "unexpected end of .ycd header"}
+		}
+		h.dataOffset += int64(len(line))
+
+		line = strings.TrimSpace(line)
+		if line == ycdEndHeader {
+			break
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		switch strings.TrimSpace(parts[0]) {
+		case "Base":
+			h.base, err = strconv.Atoi(value)
+		case "FirstDigits":
+			h.firstDigits = value
+		case "TotalDigits":
+			h.totalDigits, err = strconv.ParseInt(value, 10, 64)
+		case "Blocksize":
+			h.blockSize, err = strconv.ParseInt(value, 10, 64)
+		case "BlockID":
+			h.blockID, err = strconv.ParseInt(value, 10, 64)
+		}
+		if err != nil {
+			return nil, &CorruptDataError{-1, fmt.Sprintf("invalid .ycd header line \"%s\"", line)}
+		}
+	}
+
+	padding, err := r.ReadBytes(0)
+	if err != nil {
+		return nil, &CorruptDataError{-1, "missing start of .ycd data"}
+	}
+	h.dataOffset += int64(len(padding))
+
+	if h.base != 10 {
+		return nil, &UnsupportedFormatError{fmt.Sprintf("only base 10 .ycd files are supported, got base %d", h.base)}
+	}
+	if h.blockSize <= 0 || h.totalDigits <= 0 || h.blockID < 0 {
+		return nil, &CorruptDataError{-1, "incomplete .ycd header"}
+	}
+	return h, nil
+}
+
+// integerDigits returns the digits in front of the
+// decimal point of the FirstDigits header entry.
+func (h *ycdHeader) integerDigits() ([]byte, error) {
+	integer := strings.SplitN(h.firstDigits, ".", 2)[0]
+	if integer == "" {
+		return nil, &CorruptDataError{-1, "missing first digits in .ycd header"}
+	}
+	digits := make([]byte, len(integer))
+	for i := range integer {
+		if integer[i] < '0' || '9' < integer[i] {
+			return nil, &CorruptDataError{-1, fmt.Sprintf("invalid first digits \"%s\" in .ycd header", h.firstDigits)}
+		}
+		digits[i] = integer[i] - byte('0')
+	}
+	return digits, nil
+}
+
+type ycdChunkSource struct {
+	prefix      string
+	intDigits   []byte
+	totalDigits int64
+	blockSize   int64
+	maxSize     int
+}
+
+// NewYCDChunkSource creates a new uncached ChunkSource
+// reading the .ycd files written by y-cruncher.
+// The filename may be any of the numbered files, e.g.
+// "Pi - Dec - Chudnovsky - 0.ycd". The other files are
+// expected next to it, differing only in their number.
+//
+// Each file starts with a text header, followed by 19
+// decimal digits packed into every little endian uint64.
+// Index 0 refers to the first digit in front of the decimal
+// point, just like with the other ChunkSources.
+func NewYCDChunkSource(filename string, maxSize int) (ChunkSource, error) {
+	if !strings.HasSuffix(filename, ycdExtension) {
+		return nil, &UnsupportedFormatError{fmt.Sprintf("expected a %s file, got \"%s\"", ycdExtension, filename)}
+	}
+	base := strings.TrimSuffix(filename, ycdExtension)
+	prefix := strings.TrimRight(base, "0123456789")
+	if prefix == base {
+		return nil, &UnsupportedFormatError{fmt.Sprintf("expected a numbered %s file, got \"%s\"", ycdExtension, filename)}
+	}
+	id, err := strconv.ParseInt(base[len(prefix):], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	h, err := readYCDHeader(file)
+	if err != nil {
+		return nil, err
+	}
+	if h.blockID != id {
+		return nil, &CorruptDataError{-1, fmt.Sprintf("file \"%s\" claims to be block %d", filename, h.blockID)}
+	}
+	intDigits, err := h.integerDigits()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ycdChunkSource{
+		prefix:      prefix,
+		intDigits:   intDigits,
+		totalDigits: h.totalDigits,
+		blockSize:   h.blockSize,
+		maxSize:     maxSize,
+	}, nil
+}
+
+func (cs *ycdChunkSource) GetChunk(firstIndex int64, size int) (Chunk, error) {
+	return cs.GetChunkContext(context.Background(), firstIndex, size)
+}
+
+func (cs *ycdChunkSource) GetChunkContext(ctx context.Context, firstIndex int64, size int) (Chunk, error) {
+	err := checkChunkRequest(firstIndex, size, cs.maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	avail, _ := cs.AvailableDigits()
+	if firstIndex >= avail {
+		return nil, outOfRange(cs, firstIndex, size)
+	}
+	if int64(size) > avail-firstIndex {
+		size = int(avail - firstIndex)
+	}
+
+	chunk := &UncompressedChunk{
+		FirstDigitIndex: firstIndex,
+		Digits:          make([]byte, 0, size),
+	}
+
+	index := firstIndex
+	for ; index < int64(len(cs.intDigits)) && len(chunk.Digits) < size; index++ {
+		chunk.Digits = append(chunk.Digits, cs.intDigits[index])
+	}
+
+	for len(chunk.Digits) < size {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pos := index - int64(len(cs.intDigits))
+		block := pos / cs.blockSize
+		offset := pos % cs.blockSize
+
+		n := int64(size - len(chunk.Digits))
+		if n > cs.blockSize-offset {
+			n = cs.blockSize - offset
+		}
+
+		digits, err := cs.readBlock(block, offset, int(n))
+		if err != nil {
+			return nil, err
+		}
+		chunk.Digits = append(chunk.Digits, digits...)
+		index += n
+	}
+
+	return chunk, nil
+}
+
+// readBlock reads size digits starting at the given offset
+// from the .ycd file with the given block id.
+func (cs *ycdChunkSource) readBlock(block, offset int64, size int) ([]byte, error) {
+	filename := cs.prefix + strconv.FormatInt(block, 10) + ycdExtension
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	h, err := readYCDHeader(file)
+	if err != nil {
+		return nil, err
+	}
+	if h.blockID != block || h.blockSize != cs.blockSize {
+		return nil, &CorruptDataError{-1, fmt.Sprintf("file \"%s\" does not match the other .ycd files", filename)}
+	}
+
+	firstWord := offset / ycdDigitsPerWord
+	lastWord := (offset + int64(size) - 1) / ycdDigitsPerWord
+	data := make([]byte, (lastWord-firstWord+1)*ycdWordSize)
+
+	_, err = file.ReadAt(data, h.dataOffset+firstWord*ycdWordSize)
+	if err != nil {
+		return nil, err
+	}
+
+	digits := make([]byte, len(data)/ycdWordSize*ycdDigitsPerWord)
+	for w := 0; w*ycdWordSize < len(data); w++ {
+		word := binary.LittleEndian.Uint64(data[w*ycdWordSize:])
+		if word >= ycdMaxWord {
+			return nil, &CorruptDataError{
+				Index:  int64(len(cs.intDigits)) + block*cs.blockSize + (firstWord+int64(w))*ycdDigitsPerWord,
+				Reason: fmt.Sprintf("invalid word in file \"%s\"", filename),
+			}
+		}
+		for i := ycdDigitsPerWord - 1; i >= 0; i-- {
+			digits[w*ycdDigitsPerWord+i] = byte(word % 10)
+			word /= 10
+		}
+	}
+
+	start := int(offset % ycdDigitsPerWord)
+	return digits[start : start+size], nil
+}
+
+func (cs *ycdChunkSource) AvailableDigits() (int64, error) {
+	return int64(len(cs.intDigits)) + cs.totalDigits, nil
+}
+
+func (cs *ycdChunkSource) MaximumChunkSize() int {
+	return cs.maxSize
+}
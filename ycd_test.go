@@ -0,0 +1,98 @@
+package piio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const piDecimals = "14159265358979323846264338327950288419716939937510"
+
+func writeYCDFile(dir string, blockID, blockSize, totalDigits int, decimals string) error {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "#Compressed Digit File\r\n\r\nFileVersion:\t1.1.0\r\n\r\nBase:\t10\r\n\r\n")
+	fmt.Fprintf(buf, "FirstDigits:\t3.%s\r\n\r\nTotalDigits:\t%d\r\n\r\n", decimals[:20], totalDigits)
+	fmt.Fprintf(buf, "Blocksize:\t%d\r\nBlockID:\t%d\r\n\r\nEndHeader\r\n\r\n", blockSize, blockID)
+	buf.WriteByte(0)
+
+	for len(decimals) > 0 {
+		word := decimals
+		if len(word) > ycdDigitsPerWord {
+			word = word[:ycdDigitsPerWord]
+		}
+		decimals = decimals[len(word):]
+
+		var value uint64
+		for i := 0; i < ycdDigitsPerWord; i++ {
+			value *= 10
+			if i < len(word) {
+				value += uint64(word[i] - '0')
+			}
+		}
+		binary.Write(buf, binary.LittleEndian, value)
+	}
+
+	name := filepath.Join(dir, fmt.Sprintf("Pi - Dec - Chudnovsky - %d.ycd", blockID))
+	return ioutil.WriteFile(name, buf.Bytes(), 0644)
+}
+
+func TestYCDChunkSource(t *testing.T) {
+	Convey("Given a set of .ycd files", t, func() {
+		dir, err := ioutil.TempDir("", "piio")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		So(writeYCDFile(dir, 0, 30, 50, piDecimals[:30]), ShouldBeNil)
+		So(writeYCDFile(dir, 1, 30, 50, piDecimals[30:]), ShouldBeNil)
+
+		cs, err := NewYCDChunkSource(filepath.Join(dir, "Pi - Dec - Chudnovsky - 1.ycd"), 64)
+		So(err, ShouldBeNil)
+
+		Convey("the available digits should include the integer part.", func() {
+			avail, err := cs.AvailableDigits()
+			So(err, ShouldBeNil)
+			So(avail, ShouldEqual, 51)
+		})
+		Convey("reading across files should work.", func() {
+			c, err := cs.GetChunk(0, 51)
+			So(err, ShouldBeNil)
+			So(c.FirstIndex(), ShouldEqual, 0)
+			So(c.Length(), ShouldEqual, 51)
+
+			buf := &bytes.Buffer{}
+			So(WriteChunk(c, FileFormatText, buf), ShouldBeNil)
+			So(buf.String(), ShouldEqual, "3"+piDecimals)
+		})
+		Convey("reading at an arbitrary index should work.", func() {
+			c, err := cs.GetChunk(25, 10)
+			So(err, ShouldBeNil)
+
+			buf := &bytes.Buffer{}
+			So(WriteChunk(c, FileFormatText, buf), ShouldBeNil)
+			So(buf.String(), ShouldEqual, piDecimals[24:34])
+		})
+		Convey("reading beyond the end should trim the chunk.", func() {
+			c, err := cs.GetChunk(45, 20)
+			So(err, ShouldBeNil)
+			So(c.Length(), ShouldEqual, 6)
+
+			_, err = cs.GetChunk(51, 2)
+			So(errors.Is(err, ErrOutOfRange), ShouldBeTrue)
+		})
+		Convey("too large chunks should be rejected.", func() {
+			_, err := cs.GetChunk(0, 65)
+			So(err, ShouldNotBeNil)
+		})
+	})
+	Convey("Given a file without a number", t, func() {
+		_, err := NewYCDChunkSource("pi.ycd", 64)
+		So(err, ShouldNotBeNil)
+	})
+}